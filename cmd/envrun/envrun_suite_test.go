@@ -0,0 +1,13 @@
+package main_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestEnvRun(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "EnvRun Suite")
+}
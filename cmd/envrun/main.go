@@ -0,0 +1,227 @@
+// Command envrun runs a program with its environment modified by dotenv files or env profiles.
+//
+// Usage:
+//
+//	envrun [options] [--] command [args...]
+//
+// For example:
+//
+//	envrun -f .env -f profiles.yaml -profile ci --unset HTTP_PROXY -- go test ./...
+//	envrun -f profiles.yaml -profile ci --clean PATH,HOME --print
+//	envrun -f .env --check DATABASE_URL,API_TOKEN
+//
+// Files ending in .json, .yaml or .yml are read as profiles (see env.LoadProfile) and need
+// -profile; any other file is read as a dotenv file (see env.ParseDotEnv).  The changes are applied
+// in this order: --clean, then each file in turn, then --unset.
+//
+// With --print the changes are printed (with secret values redacted) instead of running a command.
+// With --check, envrun fails unless every listed variable would be set, before printing or running
+// anything; if no command is given it exits after the check.
+//
+// The exit status is that of the command, 1 if envrun fails, or 2 for usage errors.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/keithpaterson/go-tools/env"
+)
+
+const (
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+var (
+	errUsage = errors.New("usage")
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+type options struct {
+	files   []string
+	profile string
+	unset   listFlag
+	clean   *listFlag // nil unless --clean was given
+	print   bool
+	check   listFlag
+	command []string
+}
+
+// runs envrun with the given arguments, returning the exit status
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	opts, err := parseArgs(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "envrun: %v\n", err)
+		return exitUsage
+	}
+
+	setup, err := opts.setup()
+	if err != nil {
+		fmt.Fprintf(stderr, "envrun: %v\n", err)
+		return exitFailure
+	}
+
+	if missing := missingKeys(setup, opts.check); len(missing) > 0 {
+		fmt.Fprintf(stderr, "envrun: required variables are not set: %s\n", strings.Join(missing, ", "))
+		return exitFailure
+	}
+	if opts.print {
+		fmt.Fprintln(stdout, setup.Plan())
+		return 0
+	}
+	if len(opts.command) == 0 {
+		return 0
+	}
+
+	return runCommand(setup, opts.command, stdout, stderr)
+}
+
+func parseArgs(args []string, stderr io.Writer) (*options, error) {
+	opts := &options{}
+	flags := flag.NewFlagSet("envrun", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: envrun [options] [--] command [args...]")
+		flags.PrintDefaults()
+	}
+
+	flags.Func("f", "a dotenv `file`, or a JSON/YAML profile file (repeatable)", func(value string) error {
+		opts.files = append(opts.files, value)
+		return nil
+	})
+	flags.StringVar(&opts.profile, "profile", "", "the `name` of the profile to load from JSON/YAML files")
+	flags.Var(&opts.unset, "unset", "comma-separated `keys` to unset (repeatable)")
+	flags.Func("clean", "start from an empty environment, keeping only these comma-separated `keys` (or patterns)",
+		func(value string) error {
+			if opts.clean == nil {
+				opts.clean = &listFlag{}
+			}
+			return opts.clean.Set(value)
+		})
+	flags.BoolVar(&opts.print, "print", false, "print the changes (with secrets redacted) instead of running the command")
+	flags.Var(&opts.check, "check", "comma-separated `keys` that must be set (repeatable)")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	opts.command = flags.Args()
+
+	if len(opts.command) == 0 && !opts.print && len(opts.check) == 0 {
+		flags.Usage()
+		return nil, fmt.Errorf("%w: no command given", errUsage)
+	}
+	for _, file := range opts.files {
+		if isProfile(file) && opts.profile == "" {
+			return nil, fmt.Errorf("%w: -profile is required to load %s", errUsage, file)
+		}
+	}
+	return opts, nil
+}
+
+// builds the Setup described by the options
+func (o *options) setup() (env.Setup, error) {
+	setup := env.New()
+	if o.clean != nil {
+		setup = env.CleanRoom(*o.clean...)
+	}
+
+	for _, file := range o.files {
+		var loaded env.Setup
+		var err error
+		if isProfile(file) {
+			loaded, err = env.LoadProfile(file, o.profile)
+		} else {
+			loaded, err = env.LoadDotEnv(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		setup = append(setup, loaded...)
+	}
+
+	for _, key := range o.unset {
+		setup = setup.Unset(key)
+	}
+	return setup, nil
+}
+
+func isProfile(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// returns the keys that would not be set after applying the Setup to the process environment
+func missingKeys(setup env.Setup, keys []string) []string {
+	source := setup.Source(env.OSSource)
+	var missing []string
+	for _, key := range keys {
+		if _, ok := source.LookupEnv(key); !ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// applies the Setup to this process (so that entries such as SetFile and Chdir take effect),
+// runs the command, and reverts the Setup when it exits
+func runCommand(setup env.Setup, command []string, stdout io.Writer, stderr io.Writer) int {
+	revert, err := setup.ApplyE()
+	if err != nil {
+		fmt.Fprintf(stderr, "envrun: %v\n", err)
+		return exitFailure
+	}
+	defer revert.Apply()
+
+	cmd := exec.Command(command[0], command[1:]...) // #nosec G204 -- running the caller's command is the point
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// the command receives interrupts too; wait for it to exit rather than dying first
+	signal.Ignore(os.Interrupt)
+	defer signal.Reset(os.Interrupt)
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0:
+		return exitErr.ExitCode()
+	case err != nil:
+		fmt.Fprintf(stderr, "envrun: %v\n", err)
+		return exitFailure
+	}
+	return 0
+}
+
+// a repeatable flag whose values may also be comma-separated
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/keithpaterson/go-tools/env"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("envrun", func() {
+	var (
+		dir    string
+		stdout *bytes.Buffer
+		stderr *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+
+		origEnv := env.New().Set("__ENVRUN_KEEP__", "keep").Set("__ENVRUN_DROP__", "drop").Unset("__ENVRUN_NEW__").Apply()
+		DeferCleanup(func() { origEnv.Apply() })
+	})
+
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		return path
+	}
+
+	requireShell := func() {
+		if runtime.GOOS == "windows" {
+			Skip("requires a unix shell")
+		}
+	}
+
+	It("should run the command with the dotenv file applied", func() {
+		// Arrange
+		requireShell()
+		dotenv := write(".env", "__ENVRUN_NEW__=from dotenv\n")
+		before := env.Capture()
+
+		// Act
+		status := run([]string{"-f", dotenv, "--unset", "__ENVRUN_DROP__", "--", "sh", "-c",
+			`echo "$__ENVRUN_NEW__|$__ENVRUN_KEEP__|${__ENVRUN_DROP__-unset}"`}, stdout, stderr)
+
+		// Assert
+		Expect(status).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal("from dotenv|keep|unset\n"))
+		Expect(env.Capture()).To(Equal(before))
+	})
+
+	It("should load a named profile", func() {
+		// Arrange
+		requireShell()
+		profiles := write("profiles.yaml", "ci:\n  - {op: set, key: __ENVRUN_NEW__, value: ci}\nlocal: []\n")
+
+		// Act
+		status := run([]string{"-f", profiles, "-profile", "ci", "sh", "-c", `echo "$__ENVRUN_NEW__"`}, stdout, stderr)
+
+		// Assert
+		Expect(status).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal("ci\n"))
+	})
+
+	It("should keep only the allowlist with --clean", func() {
+		// Arrange
+		requireShell()
+
+		// Act
+		status := run([]string{"--clean", "PATH,__ENVRUN_K*", "sh", "-c", `env | grep __ENVRUN_ | sort`}, stdout, stderr)
+
+		// Assert
+		Expect(status).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal("__ENVRUN_KEEP__=keep\n"))
+	})
+
+	It("should return the command's exit status", func() {
+		// Arrange
+		requireShell()
+
+		// Act
+		status := run([]string{"sh", "-c", "exit 3"}, stdout, stderr)
+
+		// Assert
+		Expect(status).To(Equal(3))
+	})
+
+	It("should print the plan with secrets redacted", func() {
+		// Arrange
+		dotenv := write(".env", "__ENVRUN_NEW__=new\n__ENVRUN_KEEP__=changed\nAPI_TOKEN=hunter2\n")
+		origEnv := env.New().Unset("API_TOKEN").Apply()
+		defer origEnv.Apply()
+
+		// Act
+		status := run([]string{"-f", dotenv, "-unset", "__ENVRUN_DROP__", "-print", "--", "false"}, stdout, stderr)
+
+		// Assert
+		Expect(status).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal(
+			"+ __ENVRUN_NEW__=new\n~ __ENVRUN_KEEP__=keep -> changed\n+ API_TOKEN=******\n- __ENVRUN_DROP__=drop\n"))
+	})
+
+	DescribeTable("--check",
+		func(args []string, expectedStatus int, expectedErr string) {
+			// Arrange
+			dotenv := write(".env", "__ENVRUN_NEW__=new\n")
+
+			// Act
+			status := run(append([]string{"-f", dotenv}, args...), stdout, stderr)
+
+			// Assert
+			Expect(status).To(Equal(expectedStatus))
+			Expect(stderr.String()).To(Equal(expectedErr))
+		},
+		Entry("all set", []string{"-check", "__ENVRUN_NEW__,__ENVRUN_KEEP__"}, 0, ""),
+		Entry("missing", []string{"-check", "__ENVRUN_NEW__", "-check", "__ENVRUN_MISSING__", "-unset", "__ENVRUN_KEEP__",
+			"-check", "__ENVRUN_KEEP__"},
+			1, "envrun: required variables are not set: __ENVRUN_MISSING__, __ENVRUN_KEEP__\n"),
+		Entry("missing does not run the command", []string{"-check", "__ENVRUN_MISSING__", "sh", "-c", "echo ran"},
+			1, "envrun: required variables are not set: __ENVRUN_MISSING__\n"),
+		Entry("missing does not print the plan", []string{"-print", "-check", "__ENVRUN_MISSING__"},
+			1, "envrun: required variables are not set: __ENVRUN_MISSING__\n"),
+		Entry("all set prints the plan", []string{"-print", "-check", "__ENVRUN_NEW__"}, 0, ""),
+	)
+
+	DescribeTable("errors",
+		func(args []string, expectedStatus int, expectedErr string) {
+			// Act
+			status := run(args, stdout, stderr)
+
+			// Assert
+			Expect(status).To(Equal(expectedStatus))
+			Expect(stderr.String()).To(ContainSubstring(expectedErr))
+			Expect(stdout.String()).To(BeEmpty())
+		},
+		Entry("no command", []string{}, exitUsage, "envrun: usage: no command given"),
+		Entry("unknown flag", []string{"-nope", "true"}, exitUsage, "flag provided but not defined: -nope"),
+		Entry("profile without name", []string{"-f", "profiles.json", "true"}, exitUsage,
+			"envrun: usage: -profile is required to load profiles.json"),
+		Entry("missing file", []string{"-f", "/does/not/exist/.env", "true"}, exitFailure, "envrun: open /does/not/exist/.env"),
+		Entry("missing command", []string{"__envrun_no_such_command__"}, exitFailure, "envrun: exec: \"__envrun_no_such_command__\""),
+	)
+
+	It("should report invalid dotenv files", func() {
+		// Arrange
+		dotenv := write("bad.env", "FOO")
+
+		// Act
+		status := run([]string{"-f", dotenv, "true"}, stdout, stderr)
+
+		// Assert
+		Expect(status).To(Equal(exitFailure))
+		Expect(strings.TrimSpace(stderr.String())).To(HavePrefix("envrun: failed to parse dotenv: " + dotenv + ":1:"))
+	})
+})
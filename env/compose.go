@@ -0,0 +1,139 @@
+package env
+
+import (
+	"slices"
+)
+
+// Returns a new Setup with the entries of this Setup followed by those of each of the others, so
+// that where they disagree the last one wins.  For example, a base test environment with
+// per-test overrides:
+//
+//	base.Merge(env.New().Set("LOG_LEVEL", "debug"))
+//
+// Entries that have no effect because a later entry sets or unsets the same variable are dropped,
+// unless something in between depends on the variable (e.g. Rename or UnsetMatching).
+func (a Setup) Merge(others ...Setup) Setup {
+	merged := a.flatten()
+	for _, other := range others {
+		merged = append(merged, other.flatten()...)
+	}
+
+	// walk backwards, tracking the variables whose values are decided by later entries
+	decided := map[string]bool{}
+	result := New()
+	for index := len(merged) - 1; index >= 0; index-- {
+		entry := merged[index]
+		keys := entry.keys()
+		if _, ok := entry.(processState); ok {
+			// e.g. Chdir, which does not involve any variables
+			result = append(result, entry)
+			continue
+		}
+		switch entry.(type) {
+		case *addOrUpdateEnv, *removeEnv, *setFileEnv:
+			if decided[keys[0]] {
+				continue
+			}
+			decided[keys[0]] = true
+		case *defaultEnv, *listEnv, *computedEnv:
+			// these only depend on their own variable
+			if decided[keys[0]] {
+				continue
+			}
+		default:
+			if keys == nil {
+				// may depend on any variable
+				clear(decided)
+			}
+			for _, key := range keys {
+				delete(decided, key)
+			}
+		}
+		result = append(result, entry)
+	}
+	slices.Reverse(result)
+	return result
+}
+
+// Returns a new Setup without the entries that involve any of the keys.  Entries such as
+// CleanRoom, which do not name the variables they affect, are kept.
+func (a Setup) Without(keys ...string) Setup {
+	result := New()
+	for _, entry := range a.flatten() {
+		if len(intersect(entry.keys(), keys)) == 0 {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// Returns a new Setup in which every key is prefixed, to namespace the variables.  For example:
+//
+//	env.New().Set("HOST", "localhost").WithPrefix("APP_") // sets APP_HOST
+//
+// Patterns are confined to the prefixed variables: UnsetMatching("*") only removes variables that
+// start with the prefix, and CleanRoom only removes prefixed variables that are not on its
+// allowlist.
+func (a Setup) WithPrefix(prefix string) Setup {
+	result := New()
+	for _, item := range a.flatten() {
+		switch entry := item.(type) {
+		case *addOrUpdateEnv:
+			result = append(result, &addOrUpdateEnv{key: prefix + entry.key, value: entry.value, secret: entry.secret})
+		case *removeEnv:
+			result = append(result, &removeEnv{key: prefix + entry.key})
+		case *defaultEnv:
+			result = append(result, &defaultEnv{key: prefix + entry.key, value: entry.value})
+		case *listEnv:
+			result = append(result, &listEnv{key: prefix + entry.key, sep: entry.sep, value: entry.value, op: entry.op})
+		case *computedEnv:
+			result = append(result, &computedEnv{key: prefix + entry.key, fn: entry.fn})
+		case *setFileEnv:
+			result = append(result, &setFileEnv{key: prefix + entry.key, file: entry.file})
+		case *renameEnv:
+			result = append(result, &renameEnv{from: prefix + entry.from, to: prefix + entry.to, secret: entry.secret})
+		case *unsetMatchingEnv:
+			result = append(result, &unsetMatchingEnv{pattern: entry.pattern, re: entry.re, prefix: prefix + entry.prefix})
+		case *cleanRoomEnv:
+			result = append(result, &cleanRoomEnv{allowlist: entry.allowlist, prefix: prefix + entry.prefix})
+		default:
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Returns the keys of the variables that the Setup names, in the order in which they first
+// appear.  Variables affected by patterns (e.g. UnsetMatching) are not included.
+func (a Setup) Keys() []string {
+	return a.keys()
+}
+
+// Returns the value that the Setup gives the variable when applied to an empty environment, and
+// whether the variable is set.  Use Source to evaluate the Setup against another environment.
+func (a Setup) Lookup(key string) (string, bool) {
+	return a.Source(nil).LookupEnv(key)
+}
+
+// returns the entries with nested Setups (e.g. reverts) expanded in place
+// reports whether an entry marks the key's value as secret (see SetSecret)
+func (a Setup) marksSecret(key string) bool {
+	for _, entry := range a.flatten() {
+		if secret, ok := entry.(interface{ isSecret() bool }); ok && secret.isSecret() && slices.Contains(entry.keys(), key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Setup) flatten() Setup {
+	result := New()
+	for _, entry := range a {
+		if nested, ok := entry.(Setup); ok {
+			result = append(result, nested.flatten()...)
+		} else {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
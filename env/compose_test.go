@@ -0,0 +1,139 @@
+package env
+
+import (
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Composition", func() {
+	upper := func(old string, present bool) (string, bool) { return old + "!", present }
+
+	DescribeTable("Merge",
+		func(input Setup, others []Setup, expected string) {
+			Expect(input.Merge(others...).String()).To(Equal(expected))
+		},
+		Entry("nothing", New(), nil, "[]"),
+		Entry("disjoint", New().Set("A", "a"), []Setup{New().Set("B", "b"), New().Unset("C")}, "[set A=a, set B=b, unset C]"),
+		Entry("last write wins", New().Set("A", "a").Set("B", "b"), []Setup{New().Set("A", "override")},
+			"[set B=b, set A=override]"),
+		Entry("unset replaces set", New().Set("A", "a"), []Setup{New().Unset("A")}, "[unset A]"),
+		Entry("duplicates within a setup", New().Set("A", "1").Set("A", "2"), nil, "[set A=2]"),
+		Entry("later set replaces conditional entries", New().SetDefault("A", "a").Prepend("B", "/b").SetFunc("C", upper),
+			[]Setup{New().Set("A", "x").Unset("B").Set("C", "c")}, "[set A=x, unset B, set C=c]"),
+		Entry("conditional entries are kept", New().Set("A", "a"), []Setup{New().SetDefault("A", "x").Append("A", "y")},
+			"[set A=a, default A=x, append y to A]"),
+		Entry("rename depends on earlier values", New().Set("A", "a").Set("B", "b"), []Setup{New().Rename("A", "B").Set("A", "x")},
+			"[set A=a, set B=b, rename A to B, set A=x]"),
+		Entry("patterns depend on earlier values", New().Set("A", "a"), []Setup{New().UnsetMatching("*").Set("A", "x")},
+			"[set A=a, unset matching *, set A=x]"),
+		Entry("process state does not depend on variables", New().Set("A", "1").Chdir("d").TimeZone("UTC"),
+			[]Setup{New().Umask(0o022).WriteFile("f", "", 0o600).Set("A", "2")},
+			"[chdir d, time zone UTC, umask 022, write f (0 bytes, 0600), set A=2]"),
+		Entry("set replaces a file", New().SetFile("A", "x", 0o600), []Setup{New().Set("A", "a")}, "[set A=a]"),
+		Entry("nested setups are flattened", append(New(), New().Set("A", "a").Set("B", "b")), []Setup{New().Unset("B")},
+			"[set A=a, unset B]"),
+	)
+
+	It("Merge replaces earlier values with a file", func() {
+		// Act
+		merged := New().Set("A", "a").Unset("A").Merge(New().SetFile("A", "x", 0o600))
+
+		// Assert
+		Expect(merged.String()).To(MatchRegexp(`^\[set A to file \S+ \(1 bytes, 0600\)\]$`))
+	})
+
+	It("Merge does not modify its inputs", func() {
+		// Arrange
+		base := New().Set("A", "a")
+		override := New().Set("A", "b")
+
+		// Act
+		merged := base.Merge(override)
+
+		// Assert
+		Expect(merged.String()).To(Equal("[set A=b]"))
+		Expect(base.String()).To(Equal("[set A=a]"))
+		Expect(override.String()).To(Equal("[set A=b]"))
+	})
+
+	DescribeTable("Without",
+		func(input Setup, keys []string, expected string) {
+			Expect(input.Without(keys...).String()).To(Equal(expected))
+		},
+		Entry("no keys", New().Set("A", "a"), nil, "[set A=a]"),
+		Entry("every entry for the key", New().Set("A", "a").Unset("B").Prepend("A", "x").SetDefault("C", "c"), []string{"A", "C"},
+			"[unset B]"),
+		Entry("rename of either key", New().Rename("A", "B").Rename("C", "D"), []string{"B"}, "[rename C to D]"),
+		Entry("patterns are kept", CleanRoom("A").UnsetMatching("B*"), []string{"A", "B"}, "[clean room keeping [A], unset matching B*]"),
+	)
+
+	DescribeTable("WithPrefix",
+		func(input Setup, expected string) {
+			Expect(input.WithPrefix("APP_").String()).To(Equal(expected))
+		},
+		Entry("set and unset", New().Set("HOST", "localhost").Unset("PORT"), "[set APP_HOST=localhost, unset APP_PORT]"),
+		Entry("secret", New().SetSecret("KEY", "hunter2"), "[set APP_KEY=******]"),
+		Entry("conditional entries", New().SetDefault("A", "a").SetFunc("B", upper).Rename("C", "D"),
+			"[default APP_A=a, compute APP_B, rename APP_C to APP_D]"),
+		Entry("lists", New().PrependSep("A", ",", "x").AppendSep("A", ",", "y").RemoveSep("A", ",", "z"),
+			"[prepend x to APP_A, append y to APP_A, remove z from APP_A]"),
+		Entry("patterns", CleanRoom("HOST").UnsetMatching("DB_*").UnsetMatchingRegexp(regexp.MustCompile("^X")),
+			"[clean room keeping [HOST] within APP_*, unset matching DB_* within APP_*, unset matching /^X/ within APP_*]"),
+		Entry("nested prefixes", New().Set("A", "a").WithPrefix("V1_"), "[set APP_V1_A=a]"),
+	)
+
+	It("WithPrefix confines patterns to the prefix", func() {
+		// Arrange
+		base := MapSource{"HOST": "h", "DB_URL": "u", "APP_HOST": "h", "APP_DB_URL": "u", "APP_PORT": "1"}
+
+		// Act
+		keys := CleanRoom("HOST").UnsetMatching("DB_*").WithPrefix("APP_").Source(base).(keyLister).Keys()
+		regexpKeys := New().UnsetMatchingRegexp(regexp.MustCompile("^DB_")).WithPrefix("APP_").Source(base).(keyLister).Keys()
+
+		// Assert
+		Expect(keys).To(Equal([]string{"APP_HOST", "DB_URL", "HOST"}))
+		Expect(regexpKeys).To(Equal([]string{"APP_HOST", "APP_PORT", "DB_URL", "HOST"}))
+	})
+
+	It("WithPrefix keeps prefixes when serialized", func() {
+		// Arrange
+		input := CleanRoom("HOST").UnsetMatching("DB_*").WithPrefix("APP_")
+
+		// Act
+		data, err := input.MarshalJSON()
+		decoded := Setup{}
+		Expect(err).ToNot(HaveOccurred())
+		err = decoded.UnmarshalJSON(data)
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(MatchJSON(`[{"op":"clean_room","allow":["HOST"],"prefix":"APP_"},
+			{"op":"unset_matching","pattern":"DB_*","prefix":"APP_"}]`))
+		Expect(decoded).To(Equal(input))
+	})
+
+	It("Keys lists the named keys in order", func() {
+		Expect(New().Set("B", "b").Unset("A").Prepend("B", "x").Rename("C", "D").UnsetMatching("E*").Keys()).
+			To(Equal([]string{"B", "A", "C", "D"}))
+		Expect(New().Keys()).To(BeEmpty())
+	})
+
+	DescribeTable("Lookup",
+		func(input Setup, key string, expectedValue string, expectedOk bool) {
+			// Act
+			value, ok := input.Lookup(key)
+
+			// Assert
+			Expect(value).To(Equal(expectedValue))
+			Expect(ok).To(Equal(expectedOk))
+		},
+		Entry("set", New().Set("A", "a"), "A", "a", true),
+		Entry("set empty", New().Set("A", ""), "A", "", true),
+		Entry("not named", New().Set("A", "a"), "B", "", false),
+		Entry("unset", New().Set("A", "a").Unset("A"), "A", "", false),
+		Entry("last entry wins", New().Set("A", "a").Set("A", "b"), "A", "b", true),
+		Entry("evaluated", New().Set("A", "a").Append("A", "b").Rename("A", "B"), "B", "a"+ListSeparator+"b", true),
+	)
+})
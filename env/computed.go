@@ -0,0 +1,141 @@
+package env
+
+import (
+	"fmt"
+)
+
+// Adds an entry that will set the variable only if it is not already set (an existing empty
+// value counts as set).
+func (a Setup) SetDefault(key string, value interface{}) Setup {
+	return append(a, &defaultEnv{key: key, value: fmt.Sprint(value)})
+}
+
+// Adds an entry that computes the variable's new value from its current value when the Setup is
+// applied.
+//
+// 'fn' receives the current value and whether the variable is set, and returns the new value and
+// whether the variable should be set; returning false unsets the variable.  For example:
+//
+//	env.New().SetFunc("LOG_LEVEL", func(old string, present bool) (string, bool) {
+//	    return strings.ToLower(old), present
+//	})
+//
+// 'fn' is also called when the Setup is evaluated without being applied (e.g. by Plan or Source),
+// so it should not have side-effects.
+func (a Setup) SetFunc(key string, fn func(old string, present bool) (string, bool)) Setup {
+	return append(a, &computedEnv{key: key, fn: fn})
+}
+
+// Adds an entry that will move the value of variable 'from' to variable 'to', replacing any value
+// that 'to' had and unsetting 'from'.
+//
+// Nothing happens if 'from' is not set.  'to' is treated as secret if 'from' is (see SetSecret and
+// SecretKeyPatterns).
+func (a Setup) Rename(from string, to string) Setup {
+	return append(a, &renameEnv{from: from, to: to, secret: a.marksSecret(from)})
+}
+
+type defaultEnv struct {
+	key   string
+	value string
+}
+
+func (a *defaultEnv) apply(env environment) (applicator, error) {
+	if _, ok := env.lookup(a.key); ok {
+		return nil, nil
+	}
+	return (&addOrUpdateEnv{key: a.key, value: a.value}).apply(env)
+}
+
+func (a *defaultEnv) validate() error {
+	return (&addOrUpdateEnv{key: a.key, value: a.value}).validate()
+}
+
+func (a *defaultEnv) keys() []string {
+	return []string{a.key}
+}
+
+func (a *defaultEnv) String() string {
+	return fmt.Sprintf("default %s=%s", a.key, redact(a.key, a.value, false))
+}
+
+type computedEnv struct {
+	key string
+	fn  func(old string, present bool) (string, bool)
+}
+
+func (a *computedEnv) apply(env environment) (applicator, error) {
+	old, present := env.lookup(a.key)
+	value, ok := a.fn(old, present)
+	switch {
+	case !ok:
+		return (&removeEnv{key: a.key}).apply(env)
+	case present && value == old:
+		// no change
+		return nil, nil
+	}
+	computed := &addOrUpdateEnv{key: a.key, value: value}
+	if err := computed.validate(); err != nil {
+		// keys are checked up-front, but we can only check the value now
+		return nil, err
+	}
+	return computed.apply(env)
+}
+
+func (a *computedEnv) validate() error {
+	if a.fn == nil {
+		return fmt.Errorf("key %q: function is nil", a.key)
+	}
+	return validateKey(a.key)
+}
+
+func (a *computedEnv) keys() []string {
+	return []string{a.key}
+}
+
+func (a *computedEnv) String() string {
+	return fmt.Sprintf("compute %s", a.key)
+}
+
+type renameEnv struct {
+	from   string
+	to     string
+	secret bool // set if an earlier entry marked 'from' as secret
+}
+
+func (a *renameEnv) apply(env environment) (applicator, error) {
+	value, ok := env.lookup(a.from)
+	if !ok || a.from == a.to {
+		return nil, nil
+	}
+
+	// restore 'to' first; the inverter for 'from' will put the value back where it came from
+	inverters := Setup{}
+	inverter, err := (&addOrUpdateEnv{key: a.to, value: value, secret: a.isSecret()}).apply(env)
+	inverters = inverters.addInverter(inverter)
+	if err != nil {
+		return inverters, err
+	}
+	inverter, err = (&removeEnv{key: a.from, secret: a.secret}).apply(env)
+	return inverters.addInverter(inverter), err
+}
+
+// reports whether the value being moved is a secret, so that 'to' is redacted too
+func (a *renameEnv) isSecret() bool {
+	return a.secret || IsSecretKey(a.from)
+}
+
+func (a *renameEnv) validate() error {
+	if err := validateKey(a.from); err != nil {
+		return err
+	}
+	return validateKey(a.to)
+}
+
+func (a *renameEnv) keys() []string {
+	return []string{a.from, a.to}
+}
+
+func (a *renameEnv) String() string {
+	return fmt.Sprintf("rename %s to %s", a.from, a.to)
+}
@@ -0,0 +1,129 @@
+package env
+
+import (
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Computed entries", func() {
+	type expectation struct {
+		values MapSource
+		revert Setup
+	}
+
+	upper := func(old string, present bool) (string, bool) {
+		return strings.ToUpper(old), present
+	}
+
+	DescribeTable("apply",
+		func(base MapSource, input Setup, expect expectation) {
+			// Arrange
+			virtual := newVirtualEnvironment(base)
+
+			// Act
+			revert, err := input.applyTo(virtual)
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			for _, key := range []string{"FROM", "TO", "FOO"} {
+				expectedValue, expectedOk := expect.values[key]
+				value, ok := virtual.lookup(key)
+				Expect(ok).To(Equal(expectedOk), key)
+				Expect(value).To(Equal(expectedValue), key)
+			}
+			Expect(revert).To(Equal(expect.revert))
+
+			// reverting restores the base
+			_, err = revert.applyTo(virtual)
+			Expect(err).ToNot(HaveOccurred())
+			for _, key := range []string{"FROM", "TO", "FOO"} {
+				expectedValue, expectedOk := base[key]
+				value, ok := virtual.lookup(key)
+				Expect(ok).To(Equal(expectedOk), key)
+				Expect(value).To(Equal(expectedValue), key)
+			}
+		},
+		Entry("default when unset", MapSource{}, New().SetDefault("FOO", "foo"),
+			expectation{MapSource{"FOO": "foo"}, New().Unset("FOO")}),
+		Entry("default when set", MapSource{"FOO": "bar"}, New().SetDefault("FOO", "foo"),
+			expectation{MapSource{"FOO": "bar"}, New()}),
+		Entry("default when set to empty", MapSource{"FOO": ""}, New().SetDefault("FOO", "foo"),
+			expectation{MapSource{"FOO": ""}, New()}),
+		Entry("func when unset", MapSource{}, New().SetFunc("FOO", upper),
+			expectation{MapSource{}, New()}),
+		Entry("func when set", MapSource{"FOO": "foo"}, New().SetFunc("FOO", upper),
+			expectation{MapSource{"FOO": "FOO"}, New().Set("FOO", "foo")}),
+		Entry("func without change", MapSource{"FOO": "FOO"}, New().SetFunc("FOO", upper),
+			expectation{MapSource{"FOO": "FOO"}, New()}),
+		Entry("func can create", MapSource{}, New().SetFunc("FOO", func(string, bool) (string, bool) { return "new", true }),
+			expectation{MapSource{"FOO": "new"}, New().Unset("FOO")}),
+		Entry("func can unset", MapSource{"FOO": "foo"}, New().SetFunc("FOO", func(string, bool) (string, bool) { return "", false }),
+			expectation{MapSource{}, New().Set("FOO", "foo")}),
+		Entry("func sees earlier entries", MapSource{}, New().Set("FOO", "foo").SetFunc("FOO", upper),
+			expectation{MapSource{"FOO": "FOO"}, New().Unset("FOO")}),
+		Entry("rename", MapSource{"FROM": "value"}, New().Rename("FROM", "TO"),
+			expectation{MapSource{"TO": "value"}, New().Unset("TO").Set("FROM", "value")}),
+		Entry("rename replaces", MapSource{"FROM": "value", "TO": "old"}, New().Rename("FROM", "TO"),
+			expectation{MapSource{"TO": "value"}, New().Set("TO", "old").Set("FROM", "value")}),
+		Entry("rename missing", MapSource{"TO": "old"}, New().Rename("FROM", "TO"),
+			expectation{MapSource{"TO": "old"}, New()}),
+		Entry("rename to itself", MapSource{"FROM": "value"}, New().Rename("FROM", "FROM"),
+			expectation{MapSource{"FROM": "value"}, New()}),
+		Entry("rename after set", MapSource{"FROM": "orig"}, New().Set("FROM", "value").Rename("FROM", "TO"),
+			expectation{MapSource{"TO": "value"}, New().Set("FROM", "orig").Unset("TO")}),
+	)
+
+	It("should apply to the process environment", func() {
+		// Arrange
+		from := "__test_rename_from__"
+		to := "__test_rename_to__"
+		origEnv := New().Set(from, "value").Unset(to).Apply()
+		defer origEnv.Apply()
+
+		// Act
+		revert, err := New().Rename(from, to).SetDefault(from, "default").ApplyE()
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Getenv(to)).To(Equal("value"))
+		Expect(os.Getenv(from)).To(Equal("default"))
+		revert.Apply()
+		Expect(os.Getenv(from)).To(Equal("value"))
+		_, ok := os.LookupEnv(to)
+		Expect(ok).To(BeFalse())
+	})
+
+	DescribeTable("validation",
+		func(input Setup, expectedMsg string) {
+			// Act
+			_, err := input.ApplyE()
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(err.Error()).To(ContainSubstring(expectedMsg))
+		},
+		Entry("default key", New().SetDefault("A=B", "x"), `key "A=B" contains '='`),
+		Entry("default value", New().SetDefault("A", "x\x00"), `key "A": value contains NUL`),
+		Entry("nil func", New().SetFunc("A", nil), `key "A": function is nil`),
+		Entry("computed value", New().SetFunc("__test_computed__", func(string, bool) (string, bool) { return "\x00", true }),
+			`key "__test_computed__": value contains NUL`),
+		Entry("rename from", New().Rename("", "B"), "key is empty"),
+		Entry("rename to", New().Rename("A", "B=C"), `key "B=C" contains '='`),
+	)
+
+	It("should plan the changes", func() {
+		// Act
+		plan := New().SetDefault("FOO", "foo").Rename("FROM", "TO").planFor(MapSource{"FROM": "value"})
+
+		// Assert
+		Expect(plan.String()).To(Equal("+ FOO=foo\n+ TO=value\n- FROM=value"))
+	})
+
+	It("should describe the entries", func() {
+		Expect(New().SetDefault("A", "a").SetFunc("B", upper).Rename("C", "D").String()).
+			To(Equal("[default A=a, compute B, rename C to D]"))
+	})
+})
@@ -0,0 +1,85 @@
+package env
+
+import (
+	"reflect"
+	"sync"
+)
+
+// decodes a value of a registered type from an environment variable's value
+type decoder func(value string) (reflect.Value, error)
+
+var (
+	decodersMutex sync.RWMutex
+	decoders      = map[reflect.Type]decoder{}
+)
+
+// Registers a function that ResolveEnv and ResolveEnvWithName will use to decode fields of type T,
+// as well as fields of type *T and the elements of slices and maps of T.  For example:
+//
+//	env.RegisterDecoder(func(value string) (uuid.UUID, error) {
+//	    return uuid.Parse(value)
+//	})
+//
+// Registered decoders take precedence over the built-in conversions (including TextUnmarshaler),
+// and registering a decoder for a type that already has one replaces it.  Use WithDecoder to
+// decode a type differently in a single call.
+func RegisterDecoder[T any](decode func(value string) (T, error)) {
+	decodersMutex.Lock()
+	defer decodersMutex.Unlock()
+	decoders[reflect.TypeFor[T]()] = newDecoder(decode)
+}
+
+// Decodes fields of type T with the given function in this call, in preference to any decoder
+// registered with RegisterDecoder.
+func WithDecoder[T any](decode func(value string) (T, error)) ResolveOption {
+	return func(p *envpTagParser) {
+		if p.decoders == nil {
+			p.decoders = map[reflect.Type]decoder{}
+		}
+		p.decoders[reflect.TypeFor[T]()] = newDecoder(decode)
+	}
+}
+
+func newDecoder[T any](decode func(value string) (T, error)) decoder {
+	return func(value string) (reflect.Value, error) {
+		decoded, err := decode(value)
+		return reflect.ValueOf(&decoded).Elem(), err
+	}
+}
+
+// returns the decoder for the type, preferring the ones given for this call
+func (p *envpTagParser) decoderFor(t reflect.Type) (decoder, bool) {
+	if decode, ok := p.decoders[t]; ok {
+		return decode, true
+	}
+	decodersMutex.RLock()
+	defer decodersMutex.RUnlock()
+	decode, ok := decoders[t]
+	return decode, ok
+}
+
+// sets the field if there is a decoder for its type (or, for a pointer, the type it points to),
+// reporting whether there was one; an empty value leaves the field alone
+func (p *envpTagParser) setDecodedValue(field reflect.Value, value string) (bool, error) {
+	decode, ok := p.decoderFor(field.Type())
+	pointer := false
+	if !ok && field.Kind() == reflect.Pointer {
+		decode, ok = p.decoderFor(field.Type().Elem())
+		pointer = true
+	}
+	if !ok || value == "" {
+		return ok, nil
+	}
+
+	decoded, err := decode(value)
+	if err != nil {
+		return true, err
+	}
+	if pointer {
+		pointerValue := reflect.New(field.Type().Elem())
+		pointerValue.Elem().Set(decoded)
+		decoded = pointerValue
+	}
+	field.Set(decoded)
+	return true, nil
+}
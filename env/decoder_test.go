@@ -0,0 +1,122 @@
+package env
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// a type that cannot be decoded without a decoder
+type testColor struct {
+	r, g, b uint8
+}
+
+func parseTestColor(value string) (testColor, error) {
+	switch strings.ToLower(value) {
+	case "red":
+		return testColor{r: 255}, nil
+	case "green":
+		return testColor{g: 255}, nil
+	}
+	return testColor{}, errors.New("unknown color")
+}
+
+var _ = Describe("Decoders", func() {
+	type TestStruct struct {
+		Color   testColor            `envp:"color"`
+		Pointer *testColor           `envp:"pointer"`
+		Palette []testColor          `envp:"palette"`
+		Named   map[string]testColor `envp:"named"`
+		Missing testColor            `envp:"missing"`
+	}
+
+	source := MapSource{"ENV_COLOR": "red", "ENV_POINTER": "green", "ENV_PALETTE": "red, green",
+		"ENV_NAMED": "stop=red,go=green"}
+	expected := TestStruct{
+		Color:   testColor{r: 255},
+		Pointer: &testColor{g: 255},
+		Palette: []testColor{{r: 255}, {g: 255}},
+		Named:   map[string]testColor{"stop": {r: 255}, "go": {g: 255}},
+	}
+
+	registerTestColor := func() {
+		RegisterDecoder(parseTestColor)
+		DeferCleanup(func() {
+			decodersMutex.Lock()
+			defer decodersMutex.Unlock()
+			delete(decoders, reflect.TypeFor[testColor]())
+		})
+	}
+
+	It("will decode registered types, pointers, slices and maps", func() {
+		// Arrange
+		registerTestColor()
+
+		// Act
+		var s TestStruct
+		err := ResolveEnv(&s, WithSource(source))
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(expected))
+	})
+
+	It("will decode types given for the call", func() {
+		// Act
+		var s TestStruct
+		err := ResolveEnv(&s, WithSource(source), WithDecoder(parseTestColor))
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(expected))
+	})
+
+	It("will prefer decoders given for the call", func() {
+		// Arrange
+		registerTestColor()
+		blue := func(string) (testColor, error) {
+			return testColor{b: 255}, nil
+		}
+
+		// Act
+		var s TestStruct
+		err := ResolveEnv(&s, WithSource(MapSource{"ENV_COLOR": "red"}), WithDecoder(blue))
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Color).To(Equal(testColor{b: 255}))
+	})
+
+	It("will replace the built-in conversions", func() {
+		// Arrange
+		type Level int
+		type LevelStruct struct {
+			Level Level `envp:"level"`
+		}
+		levels := func(value string) (Level, error) {
+			return Level(len(value)), nil
+		}
+
+		// Act
+		var s LevelStruct
+		err := ResolveEnv(&s, WithSource(MapSource{"ENV_LEVEL": "debug"}), WithDecoder(levels))
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Level).To(Equal(Level(5)))
+	})
+
+	It("will report values that cannot be decoded", func() {
+		// Act
+		var s TestStruct
+		err := ResolveEnv(&s, WithSource(MapSource{"ENV_PALETTE": "red,mauve"}), WithDecoder(parseTestColor))
+
+		// Assert
+		Expect(err).To(MatchError(ErrEnvParseFailure))
+		Expect(err.Error()).To(Equal("failed to parse env tags: Palette (ENV_PALETTE): element 1: unknown color"))
+		Expect(s.Palette).To(BeNil())
+	})
+})
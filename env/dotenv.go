@@ -0,0 +1,304 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	ErrDotEnvParseFailure = errors.New("failed to parse dotenv")
+)
+
+// Reads a Setup from a file in dotenv format (see ParseDotEnv).
+//
+// Parse errors report the file path and line number of the offending entry.
+func LoadDotEnv(path string) (Setup, error) {
+	file, err := os.Open(path) // #nosec G304 -- reading the caller's file is the point
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseDotEnv(file)
+}
+
+// Reads a Setup from dotenv-formatted data.  Each variable in the data results in a Set entry, in
+// the order in which it appears.
+//
+// The supported syntax is:
+//
+//	# comments occupy the whole line, or follow an unquoted value after whitespace
+//	KEY=value
+//	export KEY=value          # the 'export' prefix is ignored
+//	KEY='single quoted'       # taken literally; no escapes or interpolation
+//	KEY="double \"quoted\"\n" # supports \n, \r, \t, \", \\ and \$ escapes
+//	KEY="multiple
+//	lines"                    # quoted values may span lines
+//	KEY=${OTHER}/bin          # interpolates OTHER from an earlier entry
+//
+// Interpolation of a variable that has not been defined by an earlier entry yields an empty string.
+//
+// If r has a Name() method (e.g. *os.File) then parse errors report that name along with the line
+// number.
+func ParseDotEnv(r io.Reader) (Setup, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	name := "dotenv"
+	if named, ok := r.(interface{ Name() string }); ok {
+		name = named.Name()
+	}
+
+	parser := dotEnvParser{name: name, input: []rune(string(data)), line: 1, values: map[string]string{}}
+	return parser.parse()
+}
+
+type dotEnvParser struct {
+	name   string
+	input  []rune
+	pos    int
+	line   int
+	values map[string]string // values parsed so far, for interpolation
+}
+
+func (p *dotEnvParser) parse() (Setup, error) {
+	result := New()
+	for {
+		p.skipBlankAndComments()
+		if p.eof() {
+			return result, nil
+		}
+
+		line := p.line
+		key, value, err := p.parseEntry()
+		if err != nil {
+			return nil, p.errorAt(line, err.Error())
+		}
+		p.values[key] = value
+		result = result.Set(key, value)
+	}
+}
+
+func (p *dotEnvParser) parseEntry() (string, string, error) {
+	key := p.readKey()
+	if key == "export" && p.peekSpace() {
+		p.skipSpaces()
+		key = p.readKey()
+	}
+	if key == "" {
+		// only report the name; the rest of the line may hold a secret value
+		name, _, _ := strings.Cut(p.restOfLine(), "=")
+		return "", "", fmt.Errorf("invalid variable name %q", strings.TrimSpace(name))
+	}
+
+	p.skipSpaces()
+	if p.eof() || p.peek() != '=' {
+		return "", "", fmt.Errorf("expected '=' after %q", key)
+	}
+	p.pos++
+	p.skipSpaces()
+
+	var value string
+	var err error
+	switch {
+	case p.eof():
+		// empty value
+	case p.peek() == '\'':
+		value, err = p.readSingleQuoted()
+	case p.peek() == '"':
+		value, err = p.readDoubleQuoted()
+	default:
+		value = p.readUnquoted()
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if junk := p.finishLine(); junk != "" {
+		return "", "", fmt.Errorf("unexpected characters %q after value", redact(key, junk, false))
+	}
+	return key, value, nil
+}
+
+func (p *dotEnvParser) readKey() string {
+	start := p.pos
+	for !p.eof() {
+		r := p.peek()
+		isAlpha := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		if !isAlpha && !(p.pos > start && (isDigit || r == '.')) {
+			break
+		}
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+func (p *dotEnvParser) readSingleQuoted() (string, error) {
+	p.pos++ // opening quote
+	start := p.pos
+	for !p.eof() {
+		switch p.next() {
+		case '\'':
+			return string(p.input[start : p.pos-1]), nil
+		case '\n':
+			p.line++
+		}
+	}
+	return "", errors.New("unterminated single-quoted value")
+}
+
+func (p *dotEnvParser) readDoubleQuoted() (string, error) {
+	p.pos++ // opening quote
+	var builder strings.Builder
+	for !p.eof() {
+		r := p.next()
+		switch r {
+		case '"':
+			return builder.String(), nil
+		case '\\':
+			if p.eof() {
+				continue
+			}
+			builder.WriteString(p.readEscape())
+		case '$':
+			builder.WriteString(p.readInterpolation())
+		case '\n':
+			p.line++
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return "", errors.New("unterminated double-quoted value")
+}
+
+func (p *dotEnvParser) readEscape() string {
+	r := p.next()
+	switch r {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	case '"', '\\', '$':
+		return string(r)
+	case '\n':
+		p.line++
+	}
+	// not a recognized escape; keep it as-is
+	return "\\" + string(r)
+}
+
+func (p *dotEnvParser) readUnquoted() string {
+	var builder strings.Builder
+	for !p.eof() {
+		r := p.peek()
+		if r == '\n' || (r == '#' && p.pos > 0 && isSpace(p.input[p.pos-1])) {
+			break
+		}
+		p.pos++
+		if r == '$' {
+			builder.WriteString(p.readInterpolation())
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// expects the '$' to have been consumed; resolves "${NAME}" against earlier entries.
+func (p *dotEnvParser) readInterpolation() string {
+	if p.eof() || p.peek() != '{' {
+		return "$"
+	}
+	end := p.pos + 1
+	for end < len(p.input) && p.input[end] != '}' && p.input[end] != '\n' {
+		end++
+	}
+	if end >= len(p.input) || p.input[end] != '}' {
+		// not a complete reference, treat it literally
+		return "$"
+	}
+	name := string(p.input[p.pos+1 : end])
+	p.pos = end + 1
+	return p.values[name]
+}
+
+// after a value only whitespace or a comment may follow on the same line; returns anything else
+func (p *dotEnvParser) finishLine() string {
+	p.skipSpaces()
+	if p.eof() {
+		return ""
+	}
+	switch p.peek() {
+	case '\n':
+		return ""
+	case '#':
+		p.restOfLine()
+		return ""
+	}
+	return p.restOfLine()
+}
+
+func (p *dotEnvParser) skipBlankAndComments() {
+	for !p.eof() {
+		switch r := p.peek(); {
+		case r == '\n':
+			p.line++
+			p.pos++
+		case isSpace(r):
+			p.pos++
+		case r == '#':
+			p.restOfLine()
+		default:
+			return
+		}
+	}
+}
+
+func (p *dotEnvParser) skipSpaces() {
+	for !p.eof() && isSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *dotEnvParser) restOfLine() string {
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+	return strings.TrimSpace(string(p.input[start:p.pos]))
+}
+
+func (p *dotEnvParser) peekSpace() bool {
+	return !p.eof() && isSpace(p.peek())
+}
+
+func (p *dotEnvParser) peek() rune {
+	return p.input[p.pos]
+}
+
+func (p *dotEnvParser) next() rune {
+	r := p.input[p.pos]
+	p.pos++
+	return r
+}
+
+func (p *dotEnvParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *dotEnvParser) errorAt(line int, msg string) error {
+	return fmt.Errorf("%w: %s:%d: %s", ErrDotEnvParseFailure, p.name, line, msg)
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r'
+}
@@ -0,0 +1,98 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DotEnv", func() {
+	DescribeTable("ParseDotEnv",
+		func(input string, expected Setup) {
+			// Act
+			actual, err := ParseDotEnv(strings.NewReader(input))
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("empty input", "", New()),
+		Entry("comments and blank lines", "# comment\n\n   # indented comment\n", New()),
+		Entry("simple values", "FOO=foo\nBAR = bar \n", New().Set("FOO", "foo").Set("BAR", "bar")),
+		Entry("empty value", "FOO=\nBAR=", New().Set("FOO", "").Set("BAR", "")),
+		Entry("export prefix", "export FOO=foo\nexport\tBAR=bar", New().Set("FOO", "foo").Set("BAR", "bar")),
+		Entry("variable named export", "export=yes", New().Set("export", "yes")),
+		Entry("trailing comment", "FOO=foo # comment\nBAR=b#r", New().Set("FOO", "foo").Set("BAR", "b#r")),
+		Entry("windows line endings", "FOO=foo\r\nBAR=bar\r\n", New().Set("FOO", "foo").Set("BAR", "bar")),
+		Entry("single quoted value is literal", `FOO='a \n ${BAR} # b'`, New().Set("FOO", `a \n ${BAR} # b`)),
+		Entry("double quoted value with escapes", `FOO="a\tb\n\"c\" \\ \$ \q"`, New().Set("FOO", "a\tb\n\"c\" \\ $ \\q")),
+		Entry("quoted value with comment", `FOO="foo" # comment`, New().Set("FOO", "foo")),
+		Entry("multi-line single quoted value", "FOO='one\ntwo'\nBAR=bar", New().Set("FOO", "one\ntwo").Set("BAR", "bar")),
+		Entry("multi-line double quoted value", "FOO=\"one\ntwo\"\nBAR=bar", New().Set("FOO", "one\ntwo").Set("BAR", "bar")),
+		Entry("interpolation", "FOO=foo\nBAR=${FOO}/bar\nBAZ=\"${BAR}/baz\"",
+			New().Set("FOO", "foo").Set("BAR", "foo/bar").Set("BAZ", "foo/bar/baz")),
+		Entry("interpolation of undefined variable", "FOO=a${NOPE}b", New().Set("FOO", "ab")),
+		Entry("interpolation only uses earlier entries", "FOO=${BAR}\nBAR=bar", New().Set("FOO", "").Set("BAR", "bar")),
+		Entry("incomplete interpolation is literal", "FOO=$HOME ${BAR", New().Set("FOO", "$HOME ${BAR")),
+		Entry("repeated keys", "FOO=one\nFOO=${FOO}+two", New().Set("FOO", "one").Set("FOO", "one+two")),
+	)
+
+	DescribeTable("ParseDotEnv errors",
+		func(input string, expectedMsg string) {
+			// Act
+			actual, err := ParseDotEnv(strings.NewReader(input))
+
+			// Assert
+			Expect(actual).To(BeNil())
+			Expect(err).To(MatchError(ErrDotEnvParseFailure))
+			Expect(err.Error()).To(ContainSubstring(expectedMsg))
+		},
+		Entry("missing equals", "FOO=foo\nBAR bar", "dotenv:2: expected '=' after \"BAR\""),
+		Entry("invalid name", "\n\n1FOO=foo", "dotenv:3: invalid variable name \"1FOO\""),
+		Entry("unterminated single quote", "FOO='foo\n\nBAR=bar", "dotenv:1: unterminated single-quoted value"),
+		Entry("unterminated double quote", "A=a\nFOO=\"foo", "dotenv:2: unterminated double-quoted value"),
+		Entry("junk after quoted value", "FOO=\"foo\" bar", "dotenv:1: unexpected characters \"bar\" after value"),
+		Entry("junk after secret value is redacted", "API_TOKEN=\"abc\" def", "dotenv:1: unexpected characters \"******\" after value"),
+		Entry("line numbers follow multi-line values", "FOO=\"a\nb\"\nBAR", "dotenv:3: expected '='"),
+	)
+
+	Context("LoadDotEnv", func() {
+		It("should load a file", func() {
+			// Arrange
+			path := filepath.Join(GinkgoT().TempDir(), ".env")
+			Expect(os.WriteFile(path, []byte("export FOO=foo\nBAR=\"${FOO}bar\"\n"), 0o600)).To(Succeed())
+
+			// Act
+			actual, err := LoadDotEnv(path)
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(New().Set("FOO", "foo").Set("BAR", "foobar")))
+		})
+
+		It("should report the file name and line on parse errors", func() {
+			// Arrange
+			path := filepath.Join(GinkgoT().TempDir(), ".env")
+			Expect(os.WriteFile(path, []byte("FOO=foo\n\nBAR\n"), 0o600)).To(Succeed())
+
+			// Act
+			actual, err := LoadDotEnv(path)
+
+			// Assert
+			Expect(actual).To(BeNil())
+			Expect(err).To(MatchError(ErrDotEnvParseFailure))
+			Expect(err.Error()).To(ContainSubstring(path + ":3:"))
+		})
+
+		It("should report missing files", func() {
+			// Act
+			_, err := LoadDotEnv(filepath.Join(GinkgoT().TempDir(), "missing.env"))
+
+			// Assert
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+	})
+})
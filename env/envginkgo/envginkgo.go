@@ -0,0 +1,73 @@
+// Package envginkgo integrates env.Setup with Ginkgo specs.
+//
+// These helpers live in their own package so that importing the env package does not pull in
+// Ginkgo (which registers its command-line flags when imported).
+package envginkgo
+
+import (
+	"fmt"
+
+	"github.com/keithpaterson/go-tools/env"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Applies the environment and registers the revert with DeferCleanup, so the environment is
+// restored when the current spec (or container, if called from BeforeAll/BeforeSuite) completes.
+//
+// Call this from a setup or subject node, e.g.:
+//
+//	BeforeEach(func() {
+//	    envginkgo.Apply(env.New().Set("FOO", "foo").Unset("BAR"))
+//	})
+//
+// The spec fails if the Setup cannot be applied (see env.Setup.ApplyE).
+func Apply(setup env.Setup) {
+	GinkgoHelper()
+
+	revert, err := setup.ApplyE()
+	Expect(err).ToNot(HaveOccurred(), "env setup could not be applied")
+	DeferCleanup(func() { revert.Apply() })
+}
+
+// Fails any spec that leaves the process environment changed, e.g. by calling os.Setenv without
+// reverting.  A BeforeEach captures the environment and a ReportAfterEach compares it after the
+// spec (and all of its cleanup) has run; variables whose keys match any of the 'ignore' patterns
+// (see path.Match) are not compared.
+//
+// Call it at the top level of a suite to check every spec, or within a container to check the
+// specs in that container:
+//
+//	var _ = envginkgo.DetectLeaks("GOCOVERDIR")
+//
+// Leaked changes are reverted after they are reported, so that later specs are unaffected.
+// Specs in Ordered containers are not checked, since they may legitimately share state.
+func DetectLeaks(ignore ...string) bool {
+	var snapshot env.Setup
+
+	BeforeEach(func() {
+		snapshot = env.Capture()
+	})
+	return ReportAfterEach(func(report SpecReport) {
+		if snapshot == nil || report.IsInOrderedContainer {
+			// the spec did not run, or shares state with its neighbours
+			return
+		}
+		leaked := checkLeaks(snapshot, ignore)
+		snapshot = nil
+		if leaked != "" {
+			Fail(leaked)
+		}
+	})
+}
+
+// reverts any leaked changes, and returns a description of them (or an empty string)
+func checkLeaks(snapshot env.Setup, ignore []string) string {
+	changes, revert := env.ChangesSince(snapshot, ignore...)
+	if len(changes) == 0 {
+		return ""
+	}
+	revert.Apply()
+	return fmt.Sprintf("spec leaked changes to the environment:\n%v", changes)
+}
@@ -0,0 +1,13 @@
+package envginkgo_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestEnvGinkgo(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "EnvGinkgo Suite")
+}
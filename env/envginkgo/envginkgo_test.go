@@ -0,0 +1,122 @@
+package envginkgo
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/keithpaterson/go-tools/env"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Apply", Ordered, func() {
+	const name = "__test_envginkgo_apply__"
+
+	BeforeAll(func() {
+		os.Setenv(name, "original")
+		DeferCleanup(os.Unsetenv, name)
+	})
+
+	Context("while a spec runs", func() {
+		BeforeEach(func() {
+			Apply(env.New().Set(name, "applied"))
+		})
+
+		It("should see the applied environment", func() {
+			Expect(os.Getenv(name)).To(Equal("applied"))
+		})
+	})
+
+	It("should revert the environment after the spec", func() {
+		Expect(os.Getenv(name)).To(Equal("original"))
+	})
+
+	It("should fail the spec when the setup cannot be applied", func() {
+		// Act
+		failure := InterceptGomegaFailure(func() {
+			Apply(env.New().Set("BAD=KEY", "x"))
+		})
+
+		// Assert
+		Expect(failure).To(MatchError(ContainSubstring(`key "BAD=KEY" contains '='`)))
+	})
+})
+
+var _ = Describe("DetectLeaks", func() {
+	const name = "__test_envginkgo_detect_leaks__"
+
+	DetectLeaks()
+
+	It("should pass specs that revert their changes", func() {
+		Apply(env.New().Set(name, "applied"))
+		Expect(os.Getenv(name)).To(Equal("applied"))
+	})
+
+	It("should report and revert leaked changes", func() {
+		// Arrange
+		snapshot := env.Capture()
+		os.Setenv(name, "leaked")
+
+		// Act
+		leaked := checkLeaks(snapshot, nil)
+
+		// Assert
+		Expect(leaked).To(Equal("spec leaked changes to the environment:\n+ " + name + "=leaked"))
+		_, ok := os.LookupEnv(name)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should fail specs that leak changes", func() {
+		// Arrange
+		cmd := exec.Command(os.Args[0], "-test.run=TestEnvGinkgo", "-ginkgo.focus=leak fixture", "-ginkgo.no-color")
+		cmd.Env = append(os.Environ(), leakFixtureVar+"=1")
+
+		// Act
+		output, err := cmd.CombinedOutput()
+
+		// Assert
+		Expect(err).To(HaveOccurred())
+		Expect(string(output)).To(ContainSubstring("[FAIL] leak fixture [ReportAfterEach] leaks"))
+		Expect(string(output)).To(ContainSubstring("spec leaked changes to the environment:"))
+		Expect(string(output)).To(ContainSubstring("+ " + name + "=leaked"))
+		Expect(string(output)).To(ContainSubstring("1 Passed | 1 Failed"))
+	})
+
+	It("should ignore matching keys", func() {
+		// Arrange
+		snapshot := env.Capture()
+		os.Setenv(name, "leaked")
+		DeferCleanup(os.Unsetenv, name)
+
+		// Act
+		leaked := checkLeaks(snapshot, []string{"__test_envginkgo_*"})
+
+		// Assert
+		Expect(leaked).To(BeEmpty())
+		Expect(os.Getenv(name)).To(Equal("leaked"))
+	})
+})
+
+// set in the child process started by "should fail specs that leak changes", which runs the fixture
+const leakFixtureVar = "__TEST_ENVGINKGO_LEAK_FIXTURE__"
+
+var _ = func() bool {
+	if os.Getenv(leakFixtureVar) == "" {
+		return false
+	}
+	return Describe("leak fixture", func() {
+		const name = "__test_envginkgo_detect_leaks__"
+
+		DetectLeaks(leakFixtureVar)
+
+		It("leaks", func() {
+			os.Setenv(name, "leaked")
+		})
+
+		It("is unaffected by the leak", func() {
+			_, ok := os.LookupEnv(name)
+			Expect(ok).To(BeFalse())
+		})
+	})
+}()
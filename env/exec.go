@@ -0,0 +1,43 @@
+package env
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Returns the environment that results from applying the Setup to 'base', where both are in the
+// "key=value" form used by os.Environ and exec.Cmd.Env.  The result is sorted by key.
+//
+// The process environment is neither read nor modified, so this is safe to use while other
+// goroutines are running.  Every kind of entry is supported, including list, conditional and
+// pattern entries, which are evaluated against 'base'.
+func (a Setup) Environ(base []string) []string {
+	virtual := newVirtualEnvironment(MapSource(parseEnviron(base)))
+	_, _ = a.applyTo(virtual)
+
+	result := []string{}
+	for _, key := range virtual.names() {
+		value, _ := virtual.lookup(key)
+		result = append(result, key+"="+value)
+	}
+	return result
+}
+
+// Configures the command to run with the environment that results from applying the Setup to
+// the command's environment (cmd.Env, or the process environment if that is nil, as per
+// exec.Cmd).
+//
+// The process environment is not modified.  Returns an error (matching ErrApplyFailure) if the
+// Setup has invalid entries, in which case the command is left unchanged.
+func (a Setup) ApplyToCmd(cmd *exec.Cmd) error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+
+	base := cmd.Env
+	if base == nil {
+		base = os.Environ()
+	}
+	cmd.Env = a.Environ(base)
+	return nil
+}
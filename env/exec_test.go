@@ -0,0 +1,105 @@
+package env
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exec", func() {
+	DescribeTable("Environ",
+		func(input Setup, base []string, expected []string) {
+			Expect(input.Environ(base)).To(Equal(expected))
+		},
+		Entry("empty", New(), nil, []string{}),
+		Entry("base only", New(), []string{"B=2", "A=1"}, []string{"A=1", "B=2"}),
+		Entry("set and unset", New().Set("C", "3").Set("A", "one").Unset("B"), []string{"B=2", "A=1"},
+			[]string{"A=one", "C=3"}),
+		Entry("values containing '='", New().Set("B", "x=y"), []string{"A=1=2"}, []string{"A=1=2", "B=x=y"}),
+		Entry("list and conditional entries", New().PrependSep("P", ":", "/a").SetDefault("A", "x").SetDefault("D", "d"),
+			[]string{"P=/b", "A=1"}, []string{"A=1", "D=d", "P=/a:/b"}),
+		Entry("clean room", CleanRoom("PATH").Set("FOO", "foo"), []string{"PATH=/bin", "HOME=/home", "AWS_KEY=k"},
+			[]string{"FOO=foo", "PATH=/bin"}),
+	)
+
+	It("Environ does not modify the process environment", func() {
+		// Arrange
+		name := "__test_environ__"
+		origEnv := New().Unset(name).Apply()
+		defer origEnv.Apply()
+
+		// Act
+		environ := New().Set(name, "child").Environ(os.Environ())
+
+		// Assert
+		Expect(environ).To(ContainElement(name + "=child"))
+		_, ok := os.LookupEnv(name)
+		Expect(ok).To(BeFalse())
+	})
+
+	Context("ApplyToCmd", func() {
+		It("uses the process environment when cmd.Env is nil", func() {
+			// Arrange
+			name := "__test_apply_to_cmd__"
+			origEnv := New().Set(name, "parent").Apply()
+			defer origEnv.Apply()
+			cmd := exec.Command("env")
+
+			// Act
+			err := New().Set("CHILD_ONLY", "yes").ApplyToCmd(cmd)
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmd.Env).To(ContainElements(name+"=parent", "CHILD_ONLY=yes"))
+			_, ok := os.LookupEnv("CHILD_ONLY")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("builds on cmd.Env", func() {
+			// Arrange
+			cmd := exec.Command("env")
+			cmd.Env = []string{"A=1", "B=2"}
+
+			// Act
+			err := New().Unset("A").ApplyToCmd(cmd)
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmd.Env).To(Equal([]string{"B=2"}))
+		})
+
+		It("rejects invalid setups", func() {
+			// Arrange
+			cmd := exec.Command("env")
+			cmd.Env = []string{"A=1"}
+
+			// Act
+			err := New().Set("B=C", "x").ApplyToCmd(cmd)
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(cmd.Env).To(Equal([]string{"A=1"}))
+		})
+
+		It("is seen by the child process", func() {
+			if runtime.GOOS == "windows" {
+				Skip("requires the 'env' command")
+			}
+			// Arrange
+			cmd := exec.Command("env")
+			cmd.Env = []string{"A=1"}
+			Expect(New().Set("CHILD", "yes").ApplyToCmd(cmd)).To(Succeed())
+
+			// Act
+			output, err := cmd.Output()
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.Fields(string(output))).To(ConsistOf("A=1", "CHILD=yes"))
+		})
+	})
+})
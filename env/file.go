@@ -0,0 +1,210 @@
+package env
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Adds an entry that will write a file with the given content and mode (e.g. 0o600), creating any
+// missing parent directories (with mode 0o700).
+//
+// Reverting restores the previous content and mode if the file already existed; otherwise it
+// deletes the file and the outermost directory that was created for it, along with anything that
+// has been written into that directory since.
+//
+// Like all process-state entries, this has no effect on Plan, Source, Environ or ApplyToCmd.
+func (a Setup) WriteFile(path string, content string, mode fs.FileMode) Setup {
+	return append(a, &fileEnv{path: path, content: content, mode: mode})
+}
+
+// Adds an entry that will write a file with the given content and mode into a new temporary
+// directory, and set the variable to the file's path.  For example:
+//
+//	env.New().SetFile("GOOGLE_APPLICATION_CREDENTIALS", credentials, 0o600)
+//
+// The path is chosen when the entry is added, so Plan, Source and Environ report the path that the
+// variable will have, but the file only exists while the Setup is applied to the process
+// environment.  Reverting deletes the temporary directory.
+//
+// Because the path is fixed, a Setup containing SetFile must not be applied more than once at a
+// time (e.g. by parallel tests that share a base Setup): applying it again before the first
+// application has been reverted fails, since the directory already exists.  Call SetFile in each
+// test instead.
+func (a Setup) SetFile(key string, content string, mode fs.FileMode) Setup {
+	random := make([]byte, 8)
+	_, _ = rand.Read(random)
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("env-%x", random), key)
+	return append(a, &setFileEnv{key: key, file: fileEnv{path: path, content: content, mode: mode}})
+}
+
+type fileEnv struct {
+	path    string
+	content string
+	mode    fs.FileMode
+}
+
+func (a *fileEnv) apply(env environment) (applicator, error) {
+	if _, ok := env.(processEnvironment); !ok {
+		return nil, nil
+	}
+	path, err := filepath.Abs(a.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var inverter applicator
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if info.IsDir() {
+			return nil, fmt.Errorf("file %q: is a directory", a.path)
+		}
+		previous, err := os.ReadFile(path) // #nosec G304 -- the caller chose the path
+		if err != nil {
+			return nil, err
+		}
+		inverter = &fileEnv{path: path, content: string(previous), mode: info.Mode().Perm()}
+	case errors.Is(err, fs.ErrNotExist):
+		created, err := makeParents(path)
+		if err != nil {
+			return nil, err
+		}
+		inverter = &removeFileEnv{path: path, createdDir: created}
+	default:
+		// e.g. permission denied; the original could not be restored
+		return nil, err
+	}
+
+	if err := os.WriteFile(path, []byte(a.content), a.mode); err != nil {
+		return inverter, err
+	}
+	// WriteFile only uses the mode (less the umask) when it creates the file
+	return inverter, os.Chmod(path, a.mode)
+}
+
+func (a *fileEnv) validate() error {
+	if a.path == "" {
+		return errors.New("file path is empty")
+	}
+	if a.mode&^fs.ModePerm != 0 {
+		return fmt.Errorf("file %q: mode %v: only permission bits are allowed", a.path, a.mode)
+	}
+	return nil
+}
+
+func (a *fileEnv) keys() []string {
+	return nil
+}
+
+func (a *fileEnv) state() string {
+	return "file " + a.path
+}
+
+func (a *fileEnv) String() string {
+	return fmt.Sprintf("write %s (%d bytes, %#o)", a.path, len(a.content), uint32(a.mode))
+}
+
+// creates the missing parent directories of 'path', returning the outermost one that was created
+// (or an empty string if none were)
+func makeParents(path string) (string, error) {
+	created := ""
+	for dir := filepath.Dir(path); filepath.Dir(dir) != dir; dir = filepath.Dir(dir) {
+		_, err := os.Stat(dir)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		created = dir
+	}
+	if created == "" {
+		return "", nil
+	}
+	return created, os.MkdirAll(filepath.Dir(path), 0o700)
+}
+
+type removeFileEnv struct {
+	path       string
+	createdDir string // removed along with its contents, if set
+}
+
+func (a *removeFileEnv) apply(env environment) (applicator, error) {
+	if _, ok := env.(processEnvironment); !ok {
+		return nil, nil
+	}
+	if err := os.Remove(a.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if a.createdDir != "" {
+		return nil, os.RemoveAll(a.createdDir)
+	}
+	return nil, nil
+}
+
+func (a *removeFileEnv) validate() error {
+	return nil
+}
+
+func (a *removeFileEnv) keys() []string {
+	return nil
+}
+
+func (a *removeFileEnv) state() string {
+	return "file " + a.path
+}
+
+func (a *removeFileEnv) String() string {
+	if a.createdDir != "" {
+		return fmt.Sprintf("remove %s and %s", a.path, a.createdDir)
+	}
+	return "remove " + a.path
+}
+
+type setFileEnv struct {
+	key  string
+	file fileEnv
+}
+
+func (a *setFileEnv) apply(env environment) (applicator, error) {
+	// the variable is restored before the file is removed
+	inverters := Setup{}
+	inverter, err := (&addOrUpdateEnv{key: a.key, value: a.file.path}).apply(env)
+	inverters = inverters.addInverter(inverter)
+	if err != nil {
+		return inverters, err
+	}
+	if _, ok := env.(processEnvironment); !ok {
+		return inverters, nil
+	}
+
+	// the directory is this entry's own, so it must not exist yet
+	dir := filepath.Dir(a.file.path)
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return inverters, fmt.Errorf("file %q: %w (is the Setup already applied?)", a.file.path, err)
+		}
+		return inverters, err
+	}
+	_, err = a.file.apply(env)
+	return inverters.addInverter(&removeFileEnv{path: a.file.path, createdDir: dir}), err
+}
+
+func (a *setFileEnv) validate() error {
+	if err := validateKey(a.key); err != nil {
+		return err
+	}
+	return a.file.validate()
+}
+
+func (a *setFileEnv) keys() []string {
+	return []string{a.key}
+}
+
+func (a *setFileEnv) String() string {
+	return fmt.Sprintf("set %s to file %s (%d bytes, %#o)", a.key, a.file.path, len(a.file.content), uint32(a.file.mode))
+}
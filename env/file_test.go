@@ -0,0 +1,211 @@
+package env
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File entries", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	Context("WriteFile", func() {
+		It("should create the file and its directories, and remove them on revert", func() {
+			// Arrange
+			path := filepath.Join(dir, "a", "b", "config.yaml")
+
+			// Act
+			revert, err := New().WriteFile(path, "debug: true", 0o640).ApplyE()
+			content, _ := os.ReadFile(path)
+			info, _ := os.Stat(path)
+			revert.Apply()
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("debug: true"))
+			if runtime.GOOS != "windows" {
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o640)))
+			}
+			Expect(filepath.Join(dir, "a")).ToNot(BeAnExistingFile())
+			Expect(dir).To(BeADirectory())
+		})
+
+		It("should restore an existing file", func() {
+			// Arrange
+			path := filepath.Join(dir, "config.yaml")
+			Expect(os.WriteFile(path, []byte("original"), 0o600)).To(Succeed())
+
+			// Act
+			revert, err := New().WriteFile(path, "first", 0o644).WriteFile(path, "second", 0o644).ApplyE()
+			content, _ := os.ReadFile(path)
+			revert.Apply()
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("second"))
+			content, _ = os.ReadFile(path)
+			Expect(string(content)).To(Equal("original"))
+			if runtime.GOOS != "windows" {
+				info, _ := os.Stat(path)
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+			}
+		})
+
+		It("should remove several files written into a new directory", func() {
+			// Arrange
+			sub := filepath.Join(dir, "sub")
+
+			// Act
+			revert, err := New().WriteFile(filepath.Join(sub, "a"), "a", 0o600).WriteFile(filepath.Join(sub, "b"), "b", 0o600).ApplyE()
+			revert.Apply()
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sub).ToNot(BeAnExistingFile())
+		})
+
+		It("should not replace a file that cannot be examined", func() {
+			if runtime.GOOS == "windows" {
+				Skip("symlinks need extra privileges on windows")
+			}
+			// Arrange
+			loop := filepath.Join(dir, "loop")
+			Expect(os.Symlink(loop, loop)).To(Succeed())
+
+			// Act
+			_, err := New().WriteFile(loop, "x", 0o600).ApplyE()
+			_, nestedErr := New().WriteFile(filepath.Join(loop, "child"), "x", 0o600).ApplyE()
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(nestedErr).To(MatchError(ErrApplyFailure))
+			_, statErr := os.Lstat(loop)
+			Expect(statErr).ToNot(HaveOccurred())
+		})
+
+		It("should roll back when the file cannot be written", func() {
+			// Arrange
+			name := "__test_write_file__"
+			origEnv := New().Unset(name).Apply()
+			defer origEnv.Apply()
+
+			// Act
+			_, err := New().Set(name, "x").WriteFile(filepath.Join(dir, "new"), "new", 0o600).WriteFile(dir, "x", 0o600).ApplyE()
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(err.Error()).To(ContainSubstring("is a directory"))
+			Expect(filepath.Join(dir, "new")).ToNot(BeAnExistingFile())
+			_, ok := os.LookupEnv(name)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("SetFile", func() {
+		It("should point the variable at a temporary file", func() {
+			// Arrange
+			name := "__test_set_file__"
+			origEnv := New().Set(name, "original").Apply()
+			defer origEnv.Apply()
+			setup := New().SetFile(name, `{"token": "x"}`, 0o600)
+			planned, _ := setup.Lookup(name)
+
+			// Act
+			revert, err := setup.ApplyE()
+			path := os.Getenv(name)
+			content, _ := os.ReadFile(path)
+			revert.Apply()
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal(planned))
+			Expect(filepath.Base(path)).To(Equal(name))
+			Expect(string(content)).To(Equal(`{"token": "x"}`))
+			Expect(os.Getenv(name)).To(Equal("original"))
+			Expect(filepath.Dir(path)).ToNot(BeAnExistingFile())
+		})
+
+		It("should not be applied again until it is reverted", func() {
+			// Arrange
+			name := "__test_set_file_twice__"
+			origEnv := New().Unset(name).Apply()
+			defer origEnv.Apply()
+			setup := New().SetFile(name, "x", 0o600)
+			path, _ := setup.Lookup(name)
+
+			// Act
+			first, firstErr := setup.ApplyE()
+			_, secondErr := setup.ApplyE()
+			content, _ := os.ReadFile(path)
+			first.Apply()
+
+			// Assert
+			Expect(firstErr).ToNot(HaveOccurred())
+			Expect(secondErr).To(MatchError(ErrApplyFailure))
+			Expect(secondErr).To(MatchError(os.ErrExist))
+			Expect(string(content)).To(Equal("x"))
+			Expect(filepath.Dir(path)).ToNot(BeAnExistingFile())
+			_, ok := os.LookupEnv(name)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should use a different directory for each entry", func() {
+			// Act
+			first, _ := New().SetFile("A", "", 0o600).Lookup("A")
+			second, _ := New().SetFile("A", "", 0o600).Lookup("A")
+
+			// Assert
+			Expect(first).ToNot(Equal(second))
+		})
+	})
+
+	DescribeTable("validation",
+		func(input Setup, expectedMsg string) {
+			// Act
+			_, err := input.ApplyE()
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(err.Error()).To(ContainSubstring(expectedMsg))
+		},
+		Entry("empty path", New().WriteFile("", "", 0o600), "file path is empty"),
+		Entry("mode", New().WriteFile("a", "", os.ModeDir|0o600), "only permission bits are allowed"),
+		Entry("key", New().SetFile("A=B", "", 0o600), `key "A=B" contains '='`),
+	)
+
+	It("should describe the entries without their content", func() {
+		// Arrange
+		setFile := New().SetFile("CREDS", "secret", 0o600)
+		path, _ := setFile.Lookup("CREDS")
+
+		// Act & Assert
+		Expect(New().WriteFile("/etc/app.yaml", "secret", 0o644).String()).To(Equal("[write /etc/app.yaml (6 bytes, 0644)]"))
+		Expect(setFile.String()).To(Equal("[set CREDS to file " + path + " (6 bytes, 0600)]"))
+	})
+
+	It("should serialize the entries", func() {
+		// Arrange
+		input := New().WriteFile("app.yaml", "debug: true", 0o644).SetFile("CREDS", "secret", 0o600)
+
+		// Act
+		data, err := json.Marshal(input)
+		decoded := Setup{}
+		Expect(err).ToNot(HaveOccurred())
+		err = json.Unmarshal(data, &decoded)
+
+		// Assert
+		Expect(string(data)).To(MatchJSON(`[{"op":"write_file","path":"app.yaml","value":"debug: true","mode":"0644"},
+			{"op":"set_file","key":"CREDS","value":"secret","mode":"0600"}]`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded[0]).To(Equal(input[0]))
+		Expect(decoded.String()).To(MatchRegexp(`set CREDS to file .*CREDS \(6 bytes, 0600\)`))
+	})
+})
@@ -0,0 +1,234 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Guard controls how applying a Setup to the process environment is coordinated with other
+// Setups.  Flags can be combined, e.g. SetGuard(GuardLock | GuardDetect).
+//
+// Guards only affect Setups applied to the process environment; the Setup returned from Apply
+// (the 'revert') releases the guard when it is applied.
+type Guard int
+
+const (
+	// No coordination (the default).
+	GuardNone Guard = 0
+
+	// Applying a Setup takes a process-wide lock that is held until its revert is applied, which
+	// serializes tests that run in parallel (e.g. t.Parallel()).
+	//
+	// Note that the lock is not re-entrant: applying a second Setup before reverting the first
+	// will block forever if both are applied from the same goroutine.  Combine them into a single
+	// Setup instead.
+	GuardLock Guard = 1 << iota
+
+	// Panics when two Setups that are active at the same time touch the same keys or process
+	// state (e.g. both use Chdir), or when reverts are not applied in the reverse order of their
+	// Setups (last in, first out).  Entries whose keys are not known until they are applied (e.g.
+	// CleanRoom and UnsetMatching) are treated as touching every key.
+	GuardDetect
+)
+
+var (
+	ErrGuardViolation = errors.New("env guard violation")
+)
+
+var (
+	guardMode   Guard
+	guardLock   sync.Mutex // held from apply until revert when GuardLock is set
+	guardMutex  sync.Mutex // protects the state below
+	guardActive []*guardToken
+	guardNextID uint64
+)
+
+// Sets the Guard mode for subsequent calls to Apply, and returns the previous mode.
+//
+// Typically you would call this once before running your tests (e.g. in BeforeSuite or TestMain).
+func SetGuard(mode Guard) Guard {
+	guardMutex.Lock()
+	defer guardMutex.Unlock()
+
+	previous := guardMode
+	guardMode = mode
+	return previous
+}
+
+// tracks a Setup that has been applied but not yet reverted
+type guardToken struct {
+	id     uint64
+	keys   []string
+	anyKey bool     // the Setup may touch any key (e.g. CleanRoom)
+	states []string // process state touched by the Setup (e.g. "cwd")
+	locked bool
+}
+
+func newGuardToken(a Setup) *guardToken {
+	token := &guardToken{}
+	for _, entry := range a.flatten() {
+		if state, ok := entry.(processState); ok {
+			if !slices.Contains(token.states, state.state()) {
+				token.states = append(token.states, state.state())
+			}
+			continue
+		}
+		keys := entry.keys()
+		if keys == nil {
+			// not known until applied
+			token.anyKey = true
+		}
+		for _, key := range keys {
+			if !slices.Contains(token.keys, key) {
+				token.keys = append(token.keys, key)
+			}
+		}
+	}
+	return token
+}
+
+// returns the keys and process state that both tokens touch ("*" meaning any key)
+func (t *guardToken) overlap(other *guardToken) []string {
+	var result []string
+	switch {
+	case t.anyKey && other.anyKey:
+		result = []string{"*"}
+	case t.anyKey:
+		result = slices.Clone(other.keys)
+	case other.anyKey:
+		result = slices.Clone(t.keys)
+	default:
+		result = intersect(t.keys, other.keys)
+	}
+	return append(result, intersect(t.states, other.states)...)
+}
+
+// describes what the Setup touches, for error messages
+func (t *guardToken) String() string {
+	touched := append(slices.Clone(t.keys), t.states...)
+	if t.anyKey {
+		touched = append(touched, "*")
+	}
+	return fmt.Sprintf("%q", touched)
+}
+
+// called before a Setup is applied to the process environment.  Returns nil when no guard
+// applies, which includes applying a revert (those release an existing guard instead).
+func beginGuard(a Setup) *guardToken {
+	guardMutex.Lock()
+	mode := guardMode
+	guardMutex.Unlock()
+
+	if mode == GuardNone || a.isRevert() {
+		return nil
+	}
+
+	token := newGuardToken(a)
+	if mode&GuardLock != 0 {
+		guardLock.Lock()
+		token.locked = true
+	}
+
+	guardMutex.Lock()
+	defer guardMutex.Unlock()
+	if mode&GuardDetect != 0 {
+		for _, active := range guardActive {
+			if overlap := token.overlap(active); len(overlap) > 0 {
+				token.unlock()
+				panic(fmt.Errorf("%w: setup touches %q, which an active setup has already modified", ErrGuardViolation, overlap))
+			}
+		}
+	}
+	guardNextID++
+	token.id = guardNextID
+	return token
+}
+
+// called once the Setup has been applied; registers the token and adds the release to the revert
+func (t *guardToken) commit(inverters Setup) Setup {
+	if t == nil {
+		return inverters
+	}
+
+	guardMutex.Lock()
+	defer guardMutex.Unlock()
+	guardActive = append(guardActive, t)
+	return append(inverters, &guardRelease{id: t.id})
+}
+
+// called when the Setup could not be applied
+func (t *guardToken) abort() {
+	if t != nil {
+		t.unlock()
+	}
+}
+
+func (t *guardToken) unlock() {
+	if t.locked {
+		t.locked = false
+		guardLock.Unlock()
+	}
+}
+
+// the last entry of a revert Setup, which releases the guard taken when the original was applied
+type guardRelease struct {
+	id uint64
+}
+
+func (a *guardRelease) apply(env environment) (applicator, error) {
+	if _, ok := env.(processEnvironment); !ok {
+		// virtual environments are not guarded
+		return nil, nil
+	}
+
+	guardMutex.Lock()
+	defer guardMutex.Unlock()
+
+	index := slices.IndexFunc(guardActive, func(t *guardToken) bool { return t.id == a.id })
+	if index < 0 {
+		// already released; reverting twice is harmless
+		return nil, nil
+	}
+	token := guardActive[index]
+	outOfOrder := index != len(guardActive)-1
+	guardActive = slices.Delete(guardActive, index, index+1)
+	token.unlock()
+
+	if outOfOrder && guardMode&GuardDetect != 0 {
+		// the guard is released regardless, so that the remaining reverts can still be applied
+		panic(fmt.Errorf("%w: setup for %v reverted out of order; revert setups in the reverse order they were applied",
+			ErrGuardViolation, token))
+	}
+	return nil, nil
+}
+
+func (a *guardRelease) validate() error {
+	return nil
+}
+
+func (a *guardRelease) keys() []string {
+	return nil
+}
+
+func (a *guardRelease) String() string {
+	return "release guard"
+}
+
+func (a Setup) isRevert() bool {
+	return slices.ContainsFunc(a, func(entry applicator) bool {
+		_, ok := entry.(*guardRelease)
+		return ok
+	})
+}
+
+func intersect(a []string, b []string) []string {
+	var result []string
+	for _, key := range a {
+		if slices.Contains(b, key) && !slices.Contains(result, key) {
+			result = append(result, key)
+		}
+	}
+	return result
+}
@@ -0,0 +1,188 @@
+package env
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Guard", func() {
+	const (
+		nameA = "__test_guard_a__"
+		nameB = "__test_guard_b__"
+	)
+
+	BeforeEach(func() {
+		origEnv := New().Unset(nameA).Unset(nameB).Apply()
+		DeferCleanup(func() { origEnv.Apply() })
+	})
+
+	useGuard := func(mode Guard) {
+		previous := SetGuard(mode)
+		DeferCleanup(func() { SetGuard(previous) })
+	}
+
+	It("should not change reverts when no guard is set", func() {
+		// Arrange
+		useGuard(GuardNone)
+
+		// Act
+		revert := New().Set(nameA, "a").Apply()
+
+		// Assert
+		Expect(revert).To(Equal(New().Unset(nameA)))
+		revert.Apply()
+	})
+
+	Context("GuardLock", func() {
+		BeforeEach(func() {
+			useGuard(GuardLock)
+		})
+
+		It("should hold the lock until the revert is applied", func() {
+			// Arrange
+			revert := New().Set(nameA, "a").Apply()
+			applied := make(chan Setup)
+
+			// Act
+			go func() {
+				defer GinkgoRecover()
+				applied <- New().Set(nameB, "b").Apply()
+			}()
+
+			// Assert
+			Consistently(applied, 50*time.Millisecond).ShouldNot(Receive())
+			Expect(os.Getenv(nameB)).To(BeEmpty())
+
+			revert.Apply()
+			var otherRevert Setup
+			Eventually(applied).Should(Receive(&otherRevert))
+			Expect(os.Getenv(nameB)).To(Equal("b"))
+			otherRevert.Apply()
+		})
+
+		It("should release the lock when ApplyE fails", func() {
+			// Act
+			revert, err := New().Set(nameA, "a").Set("BAD=KEY", "x").ApplyE()
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(revert).To(BeNil())
+			done := make(chan Setup)
+			go func() {
+				done <- New().Set(nameA, "a").Apply()
+			}()
+			Eventually(done).Should(Receive(&revert))
+			revert.Apply()
+		})
+
+		It("should tolerate reverting twice", func() {
+			// Arrange
+			revert := New().Set(nameA, "a").Apply()
+
+			// Act
+			revert.Apply()
+			revert.Apply()
+
+			// Assert
+			New().Set(nameB, "b").Apply().Apply()
+			_, ok := os.LookupEnv(nameA)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should not guard virtual evaluation", func() {
+			// Arrange
+			revert := New().Set(nameA, "a").Apply()
+			defer revert.Apply()
+
+			// Act
+			value, ok := revert.Source(MapSource{nameA: "x"}).LookupEnv(nameA)
+
+			// Assert
+			Expect(ok).To(BeFalse())
+			Expect(value).To(BeEmpty())
+		})
+	})
+
+	Context("GuardDetect", func() {
+		BeforeEach(func() {
+			useGuard(GuardDetect)
+		})
+
+		It("should allow nested setups with different keys", func() {
+			// Act
+			revertA := New().Set(nameA, "a").Apply()
+			revertB := New().Set(nameB, "b").Apply()
+
+			// Assert
+			Expect(func() {
+				revertB.Apply()
+				revertA.Apply()
+			}).ToNot(Panic())
+		})
+
+		It("should panic when active setups touch the same keys", func() {
+			// Arrange
+			revertA := New().Set(nameA, "a").Apply()
+			defer revertA.Apply()
+
+			// Act & Assert
+			Expect(func() {
+				New().Set(nameB, "b").Unset(nameA).Apply()
+			}).To(PanicWith(And(
+				MatchError(ErrGuardViolation),
+				MatchError(ContainSubstring(`["__test_guard_a__"]`)),
+			)))
+			Expect(os.Getenv(nameA)).To(Equal("a"))
+			Expect(os.Getenv(nameB)).To(BeEmpty())
+		})
+
+		It("should panic when an active setup touches keys that another may remove", func() {
+			// Arrange
+			revertA := New().Set(nameA, "a").Apply()
+			defer revertA.Apply()
+
+			// Act & Assert
+			Expect(func() {
+				CleanRoom("PATH", "HOME").Apply()
+			}).To(PanicWith(And(
+				MatchError(ErrGuardViolation),
+				MatchError(ContainSubstring(`["__test_guard_a__"]`)),
+			)))
+			Expect(os.Getenv(nameA)).To(Equal("a"))
+		})
+
+		It("should panic when active setups change the same process state", func() {
+			// Arrange
+			revertA := New().Chdir(os.TempDir()).Apply()
+			defer revertA.Apply()
+
+			// Act & Assert
+			Expect(func() {
+				New().Set(nameB, "b").Chdir(os.TempDir()).Apply()
+			}).To(PanicWith(And(
+				MatchError(ErrGuardViolation),
+				MatchError(ContainSubstring(`["cwd"]`)),
+			)))
+			Expect(func() {
+				New().TimeZone("UTC").Apply().Apply()
+			}).ToNot(Panic())
+		})
+
+		It("should panic when reverts are applied out of order", func() {
+			// Arrange
+			revertA := New().Set(nameA, "a").Apply()
+			revertB := New().Set(nameB, "b").Apply()
+			defer revertB.Apply()
+
+			// Act & Assert
+			Expect(func() {
+				revertA.Apply()
+			}).To(PanicWith(MatchError(ErrGuardViolation)))
+			_, ok := os.LookupEnv(nameA)
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,110 @@
+package env
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The variable that tells a re-executed test binary which test RunIsolated should run in-process.
+const isolatedTestVar = "GO_TOOLS_ENV_ISOLATED_TEST"
+
+// Runs testFunc in a child process with the Setup applied to the child's environment from the
+// start, for code that reads the environment during init() or caches it in package variables.
+//
+// The current test binary is re-executed with only the current test selected (-test.run); in the
+// child, RunIsolated recognizes the test and calls testFunc directly.  The child's output is
+// logged to t as it arrives, and the test fails if the child fails (or is skipped if the child
+// skips).  The parent's environment is not modified.
+//
+// Since the whole test function runs again in the child, call RunIsolated at the start of the
+// test, and at most once per test; use subtests to run several isolated functions:
+//
+//	func TestConfig(t *testing.T) {
+//	    t.Run("debug", func(t *testing.T) {
+//	        env.RunIsolated(t, env.New().Set("LOG_LEVEL", "debug"), func(t *testing.T) {
+//	            ... // package variables were initialized with LOG_LEVEL=debug
+//	        })
+//	    })
+//	}
+func RunIsolated(t *testing.T, setup Setup, testFunc func(t *testing.T)) {
+	t.Helper()
+
+	if os.Getenv(isolatedTestVar) == t.Name() {
+		// we are the child
+		testFunc(t)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run="+isolatedRunPattern(t.Name()), "-test.v") // #nosec G204 -- re-running ourselves
+	if err := setup.Set(isolatedTestVar, t.Name()).ApplyToCmd(cmd); err != nil {
+		t.Fatalf("env: %v", err)
+		return
+	}
+	output := &testLogWriter{t: t}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err := cmd.Run()
+	output.flush()
+
+	passed := strings.Contains(output.all.String(), "--- PASS: "+t.Name()+" ")
+	skipped := strings.Contains(output.all.String(), "--- SKIP: "+t.Name()+" ")
+	switch {
+	case err != nil:
+		t.Errorf("env: isolated test failed: %v", err)
+	case skipped:
+		t.Skip("env: isolated test was skipped")
+	case !passed:
+		t.Errorf("env: isolated test %q did not run in the child process", t.Name())
+	}
+}
+
+// builds a -test.run pattern that matches exactly the named test (each level of a subtest name is
+// matched separately)
+func isolatedRunPattern(name string) string {
+	levels := strings.Split(name, "/")
+	for index, level := range levels {
+		levels[index] = "^" + regexp.QuoteMeta(level) + "$"
+	}
+	return strings.Join(levels, "/")
+}
+
+// logs each complete line written to it, and keeps a copy of everything
+type testLogWriter struct {
+	t       *testing.T
+	mutex   sync.Mutex
+	all     bytes.Buffer
+	pending []byte
+}
+
+func (w *testLogWriter) Write(data []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.all.Write(data)
+	w.pending = append(w.pending, data...)
+	for {
+		index := bytes.IndexByte(w.pending, '\n')
+		if index < 0 {
+			break
+		}
+		w.t.Log(string(w.pending[:index]))
+		w.pending = w.pending[index+1:]
+	}
+	return len(data), nil
+}
+
+func (w *testLogWriter) flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.pending) > 0 {
+		w.t.Log(string(w.pending))
+		w.pending = nil
+	}
+}
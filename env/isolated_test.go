@@ -0,0 +1,40 @@
+package env
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// read during package initialization, which is too early for an in-process Setup
+var isolatedInitValue = os.Getenv("__TEST_RUN_ISOLATED__")
+
+// RunIsolated re-executes the test binary for the current *testing.T, so it is tested with a plain
+// test function rather than a Ginkgo spec.
+func TestRunIsolated(t *testing.T) {
+	t.Run("applies the setup before init", func(t *testing.T) {
+		RunIsolated(t, New().Set("__TEST_RUN_ISOLATED__", "isolated"), func(t *testing.T) {
+			if isolatedInitValue != "isolated" {
+				t.Errorf("expected the value to be set during init, got %q", isolatedInitValue)
+			}
+		})
+	})
+
+	// this part of the test also runs in the child, where the variable is set
+	if _, ok := os.LookupEnv("__TEST_RUN_ISOLATED__"); ok && os.Getenv(isolatedTestVar) == "" {
+		t.Errorf("the parent environment was modified")
+	}
+}
+
+var _ = Describe("Isolated", func() {
+	DescribeTable("isolatedRunPattern",
+		func(name string, expected string) {
+			Expect(isolatedRunPattern(name)).To(Equal(expected))
+		},
+		Entry("test", "TestFoo", "^TestFoo$"),
+		Entry("subtest", "TestFoo/bar_baz", "^TestFoo$/^bar_baz$"),
+		Entry("special characters", "TestFoo/a.b(c)#01", `^TestFoo$/^a\.b\(c\)#01$`),
+	)
+})
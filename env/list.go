@@ -0,0 +1,162 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	// The separator used by Prepend, Append and Remove; this is os.PathListSeparator
+	// (':' on unix, ';' on windows).
+	ListSeparator = string(os.PathListSeparator)
+)
+
+// Adds an entry that will insert 'value' at the start of a list-valued variable such as PATH,
+// using ListSeparator.  See PrependSep for details.
+func (a Setup) Prepend(key string, value string) Setup {
+	return a.PrependSep(key, ListSeparator, value)
+}
+
+// Adds an entry that will add 'value' at the end of a list-valued variable such as PATH, using
+// ListSeparator.  See AppendSep for details.
+func (a Setup) Append(key string, value string) Setup {
+	return a.AppendSep(key, ListSeparator, value)
+}
+
+// Adds an entry that will remove 'value' from a list-valued variable such as PATH, using
+// ListSeparator.  See RemoveSep for details.
+func (a Setup) Remove(key string, value string) Setup {
+	return a.RemoveSep(key, ListSeparator, value)
+}
+
+// Adds an entry that will insert 'value' at the start of the list held by the variable, where
+// list elements are separated by 'sep'.
+//
+// 'value' may contain several elements.  Elements that are already in the list are moved rather
+// than duplicated (the resulting list never contains duplicates), and the variable is created if
+// it does not exist.
+//
+// For example, to put your tools first on the GOFLAGS list:
+//
+//	env.New().PrependSep("GOFLAGS", " ", "-tags=testutils")
+func (a Setup) PrependSep(key string, sep string, value string) Setup {
+	return append(a, &listEnv{key: key, sep: sep, value: value, op: listPrepend})
+}
+
+// Adds an entry that will add 'value' to the end of the list held by the variable, where list
+// elements are separated by 'sep'.
+//
+// 'value' may contain several elements.  Elements that are already in the list are moved rather
+// than duplicated, and the variable is created if it does not exist.
+func (a Setup) AppendSep(key string, sep string, value string) Setup {
+	return append(a, &listEnv{key: key, sep: sep, value: value, op: listAppend})
+}
+
+// Adds an entry that will remove every occurrence of 'value' from the list held by the variable,
+// where list elements are separated by 'sep'.
+//
+// 'value' may contain several elements.  The variable is left set (possibly to an empty value)
+// even when every element has been removed.
+func (a Setup) RemoveSep(key string, sep string, value string) Setup {
+	return append(a, &listEnv{key: key, sep: sep, value: value, op: listRemove})
+}
+
+type listOp int
+
+const (
+	listPrepend listOp = iota
+	listAppend
+	listRemove
+)
+
+func (o listOp) String() string {
+	switch o {
+	case listPrepend:
+		return "prepend"
+	case listAppend:
+		return "append"
+	}
+	return "remove"
+}
+
+type listEnv struct {
+	key   string
+	sep   string
+	value string
+	op    listOp
+}
+
+func (a *listEnv) apply(env environment) (applicator, error) {
+	current, ok := env.lookup(a.key)
+	if !ok && a.op == listRemove {
+		// nothing to remove
+		return nil, nil
+	}
+
+	// capture the original value so that the revert is exact
+	var inverter applicator = &removeEnv{key: a.key}
+	if ok {
+		inverter = &addOrUpdateEnv{key: a.key, value: current}
+	}
+
+	elements := splitList(current, a.sep)
+	values := splitList(a.value, a.sep)
+	elements = slices.DeleteFunc(elements, func(element string) bool {
+		return slices.Contains(values, element)
+	})
+	switch a.op {
+	case listPrepend:
+		elements = append(values, elements...)
+	case listAppend:
+		elements = append(elements, values...)
+	}
+
+	if err := env.set(a.key, strings.Join(elements, a.sep)); err != nil {
+		return nil, err
+	}
+	return inverter, nil
+}
+
+func (a *listEnv) validate() error {
+	if err := validateKey(a.key); err != nil {
+		return err
+	}
+	if a.sep == "" {
+		return fmt.Errorf("key %q: list separator is empty", a.key)
+	}
+	if strings.ContainsRune(a.value, 0) {
+		return fmt.Errorf("key %q: value contains NUL", a.key)
+	}
+	return nil
+}
+
+func (a *listEnv) keys() []string {
+	return []string{a.key}
+}
+
+func (a *listEnv) String() string {
+	value := redact(a.key, a.value, false)
+	switch a.op {
+	case listPrepend:
+		return fmt.Sprintf("prepend %s to %s", value, a.key)
+	case listAppend:
+		return fmt.Sprintf("append %s to %s", value, a.key)
+	}
+	return fmt.Sprintf("remove %s from %s", value, a.key)
+}
+
+// splits the list, de-duplicating its elements (but keeping their order)
+func splitList(list string, sep string) []string {
+	var elements []string
+	if list == "" {
+		return elements
+	}
+	for _, element := range strings.Split(list, sep) {
+		if !slices.Contains(elements, element) {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
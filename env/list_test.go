@@ -0,0 +1,91 @@
+package env
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("List entries", func() {
+	type expectation struct {
+		value  string
+		ok     bool
+		revert Setup
+	}
+
+	DescribeTable("apply",
+		func(base MapSource, input Setup, expect expectation) {
+			// Arrange
+			virtual := newVirtualEnvironment(base)
+
+			// Act
+			revert, err := input.applyTo(virtual)
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			value, ok := virtual.lookup("LIST")
+			Expect(value).To(Equal(expect.value))
+			Expect(ok).To(Equal(expect.ok))
+			Expect(revert).To(Equal(expect.revert))
+		},
+		Entry("prepend to missing", MapSource{}, New().PrependSep("LIST", ":", "a"),
+			expectation{"a", true, New().Unset("LIST")}),
+		Entry("prepend to empty", MapSource{"LIST": ""}, New().PrependSep("LIST", ":", "a"),
+			expectation{"a", true, New().Set("LIST", "")}),
+		Entry("prepend", MapSource{"LIST": "b:c"}, New().PrependSep("LIST", ":", "a"),
+			expectation{"a:b:c", true, New().Set("LIST", "b:c")}),
+		Entry("prepend moves existing", MapSource{"LIST": "b:a:c"}, New().PrependSep("LIST", ":", "a"),
+			expectation{"a:b:c", true, New().Set("LIST", "b:a:c")}),
+		Entry("prepend several", MapSource{"LIST": "b:c"}, New().PrependSep("LIST", ":", "a:c:a"),
+			expectation{"a:c:b", true, New().Set("LIST", "b:c")}),
+		Entry("append", MapSource{"LIST": "a:b"}, New().AppendSep("LIST", ":", "c"),
+			expectation{"a:b:c", true, New().Set("LIST", "a:b")}),
+		Entry("append moves existing", MapSource{"LIST": "c:a:b"}, New().AppendSep("LIST", ":", "c"),
+			expectation{"a:b:c", true, New().Set("LIST", "c:a:b")}),
+		Entry("append de-duplicates", MapSource{"LIST": "a:b:a"}, New().AppendSep("LIST", ":", "c"),
+			expectation{"a:b:c", true, New().Set("LIST", "a:b:a")}),
+		Entry("append to missing", MapSource{}, New().AppendSep("LIST", ":", "c"),
+			expectation{"c", true, New().Unset("LIST")}),
+		Entry("remove", MapSource{"LIST": "a:b:a:c"}, New().RemoveSep("LIST", ":", "a"),
+			expectation{"b:c", true, New().Set("LIST", "a:b:a:c")}),
+		Entry("remove everything", MapSource{"LIST": "a"}, New().RemoveSep("LIST", ":", "a"),
+			expectation{"", true, New().Set("LIST", "a")}),
+		Entry("remove from missing", MapSource{}, New().RemoveSep("LIST", ":", "a"),
+			expectation{"", false, New()}),
+		Entry("custom separator", MapSource{"LIST": "-v -race"}, New().PrependSep("LIST", " ", "-tags=x"),
+			expectation{"-tags=x -v -race", true, New().Set("LIST", "-v -race")}),
+		Entry("combined", MapSource{"LIST": "b"}, New().PrependSep("LIST", ":", "a").AppendSep("LIST", ":", "c").RemoveSep("LIST", ":", "b"),
+			expectation{"a:c", true, New().Set("LIST", "b")}),
+	)
+
+	It("should use the path list separator by default", func() {
+		// Arrange
+		name := "__test_list_path__"
+		sep := string(os.PathListSeparator)
+		origEnv := New().Set(name, "/usr/bin"+sep+"/bin").Apply()
+		defer origEnv.Apply()
+
+		// Act
+		revert := New().Prepend(name, "/opt/tools").Append(name, "/sbin").Remove(name, "/usr/bin").Apply()
+
+		// Assert
+		Expect(os.Getenv(name)).To(Equal("/opt/tools" + sep + "/bin" + sep + "/sbin"))
+		revert.Apply()
+		Expect(os.Getenv(name)).To(Equal("/usr/bin" + sep + "/bin"))
+	})
+
+	It("should reject an empty separator", func() {
+		// Act
+		_, err := New().PrependSep("LIST", "", "a").ApplyE()
+
+		// Assert
+		Expect(err).To(MatchError(ErrApplyFailure))
+		Expect(err.Error()).To(ContainSubstring(`key "LIST": list separator is empty`))
+	})
+
+	It("should describe the entries", func() {
+		Expect(New().PrependSep("PATH", ":", "/a").AppendSep("PATH", ":", "/b").RemoveSep("PATH", ":", "/c").String()).
+			To(Equal("[prepend /a to PATH, append /b to PATH, remove /c from PATH]"))
+	})
+})
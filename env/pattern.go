@@ -0,0 +1,143 @@
+package env
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Returns a Setup that removes every variable from the environment except those in the
+// allowlist, which may contain exact keys or glob patterns (see path.Match).  For example:
+//
+//	revert := env.CleanRoom("PATH", "HOME", "LC_*").Set("FOO", "foo").Apply()
+//
+// The environment is examined when the Setup is applied, and the revert restores every variable
+// that was removed.
+func CleanRoom(allowlist ...string) Setup {
+	return append(New(), &cleanRoomEnv{allowlist: allowlist})
+}
+
+// Adds an entry that will remove every variable whose key matches the glob pattern (see
+// path.Match), e.g. "AWS_*".
+//
+// The environment is examined when the Setup is applied, and the revert restores every variable
+// that was removed.
+func (a Setup) UnsetMatching(pattern string) Setup {
+	return append(a, &unsetMatchingEnv{pattern: pattern})
+}
+
+// Adds an entry that will remove every variable whose key matches the regular expression.
+//
+// The environment is examined when the Setup is applied, and the revert restores every variable
+// that was removed.
+func (a Setup) UnsetMatchingRegexp(re *regexp.Regexp) Setup {
+	return append(a, &unsetMatchingEnv{re: re})
+}
+
+type unsetMatchingEnv struct {
+	pattern string         // glob pattern, used when re is nil
+	re      *regexp.Regexp // regular expression
+	prefix  string         // only keys with this prefix are matched, after removing it (see WithPrefix)
+}
+
+func (a *unsetMatchingEnv) apply(env environment) (applicator, error) {
+	return unsetWhere(env, a.matches)
+}
+
+func (a *unsetMatchingEnv) matches(key string) bool {
+	key, ok := strings.CutPrefix(key, a.prefix)
+	if !ok {
+		return false
+	}
+	if a.re != nil {
+		return a.re.MatchString(key)
+	}
+	matched, _ := path.Match(a.pattern, key)
+	return matched
+}
+
+func (a *unsetMatchingEnv) validate() error {
+	if a.re != nil {
+		return nil
+	}
+	if _, err := path.Match(a.pattern, ""); err != nil {
+		return fmt.Errorf("pattern %q: %w", a.pattern, err)
+	}
+	return nil
+}
+
+func (a *unsetMatchingEnv) keys() []string {
+	// not known until applied
+	return nil
+}
+
+func (a *unsetMatchingEnv) String() string {
+	if a.re != nil {
+		return fmt.Sprintf("unset matching /%s/%s", a.re, within(a.prefix))
+	}
+	return "unset matching " + a.pattern + within(a.prefix)
+}
+
+type cleanRoomEnv struct {
+	allowlist []string
+	prefix    string // only keys with this prefix are removed, and allowed without it (see WithPrefix)
+}
+
+func (a *cleanRoomEnv) apply(env environment) (applicator, error) {
+	return unsetWhere(env, func(key string) bool {
+		key, ok := strings.CutPrefix(key, a.prefix)
+		return ok && !a.allowed(key)
+	})
+}
+
+func (a *cleanRoomEnv) allowed(key string) bool {
+	for _, pattern := range a.allowlist {
+		if matched, _ := path.Match(pattern, key); matched || pattern == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *cleanRoomEnv) validate() error {
+	for _, pattern := range a.allowlist {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (a *cleanRoomEnv) keys() []string {
+	// not known until applied
+	return nil
+}
+
+func (a *cleanRoomEnv) String() string {
+	return fmt.Sprintf("clean room keeping [%s]%s", strings.Join(a.allowlist, ", "), within(a.prefix))
+}
+
+func within(prefix string) string {
+	if prefix == "" {
+		return ""
+	}
+	return fmt.Sprintf(" within %s*", prefix)
+}
+
+// unsets every variable in the environment that satisfies the predicate, returning a composite
+// inverter that restores them
+func unsetWhere(env environment, predicate func(key string) bool) (applicator, error) {
+	inverters := Setup{}
+	for _, key := range env.names() {
+		if !predicate(key) {
+			continue
+		}
+		inverter, err := (&removeEnv{key: key}).apply(env)
+		inverters = inverters.addInverter(inverter)
+		if err != nil {
+			return inverters, err
+		}
+	}
+	return inverters, nil
+}
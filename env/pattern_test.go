@@ -0,0 +1,118 @@
+package env
+
+import (
+	"os"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pattern entries", func() {
+	base := MapSource{"AWS_REGION": "us-east-1", "AWS_PROFILE": "dev", "HOME": "/home/me", "PATH": "/bin", "LC_ALL": "C"}
+
+	DescribeTable("apply",
+		func(input Setup, expectedKeys []string, expectedRevert Setup) {
+			// Arrange
+			virtual := newVirtualEnvironment(base)
+
+			// Act
+			revert, err := input.applyTo(virtual)
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(virtual.names()).To(Equal(expectedKeys))
+			Expect(revert).To(Equal(expectedRevert))
+
+			_, err = revert.applyTo(virtual)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(input.Source(base).(*setupSource).evaluate().names()).To(Equal(expectedKeys))
+			Expect(virtual.names()).To(Equal(base.Keys()))
+		},
+		Entry("unset matching glob", New().UnsetMatching("AWS_*"),
+			[]string{"HOME", "LC_ALL", "PATH"},
+			New().Set("AWS_PROFILE", "dev").Set("AWS_REGION", "us-east-1")),
+		Entry("unset matching nothing", New().UnsetMatching("GCP_*"),
+			[]string{"AWS_PROFILE", "AWS_REGION", "HOME", "LC_ALL", "PATH"},
+			New()),
+		Entry("unset matching regexp", New().UnsetMatchingRegexp(regexp.MustCompile(`^(AWS_R|LC_)`)),
+			[]string{"AWS_PROFILE", "HOME", "PATH"},
+			New().Set("AWS_REGION", "us-east-1").Set("LC_ALL", "C")),
+		Entry("unset matching sees earlier entries", New().Set("AWS_NEW", "new").UnsetMatching("AWS_*"),
+			[]string{"HOME", "LC_ALL", "PATH"},
+			New().Unset("AWS_NEW").Set("AWS_PROFILE", "dev").Set("AWS_REGION", "us-east-1")),
+		Entry("clean room", CleanRoom("PATH", "HOME"),
+			[]string{"HOME", "PATH"},
+			New().Set("AWS_PROFILE", "dev").Set("AWS_REGION", "us-east-1").Set("LC_ALL", "C")),
+		Entry("clean room with patterns", CleanRoom("LC_*", "AWS_PROFILE"),
+			[]string{"AWS_PROFILE", "LC_ALL"},
+			New().Set("AWS_REGION", "us-east-1").Set("HOME", "/home/me").Set("PATH", "/bin")),
+		Entry("clean room then set", CleanRoom().Set("FOO", "foo"),
+			[]string{"FOO"},
+			New().Set("AWS_PROFILE", "dev").Set("AWS_REGION", "us-east-1").Set("HOME", "/home/me").
+				Set("LC_ALL", "C").Set("PATH", "/bin").Unset("FOO")),
+	)
+
+	It("should evaluate against the live environment", func() {
+		// Arrange
+		before := Capture()
+		origEnv := New().Set("__TEST_PATTERN_A__", "a").Set("__TEST_PATTERN_B__", "b").Apply()
+		defer origEnv.Apply()
+
+		// Act
+		revert, err := New().UnsetMatching("__TEST_PATTERN_*").ApplyE()
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		_, ok := os.LookupEnv("__TEST_PATTERN_A__")
+		Expect(ok).To(BeFalse())
+		_, ok = os.LookupEnv("__TEST_PATTERN_B__")
+		Expect(ok).To(BeFalse())
+		revert.Apply()
+		Expect(os.Getenv("__TEST_PATTERN_A__")).To(Equal("a"))
+		Expect(os.Getenv("__TEST_PATTERN_B__")).To(Equal("b"))
+		origEnv.Apply()
+		Expect(Capture()).To(Equal(before))
+	})
+
+	It("should produce a complete revert for a clean room", func() {
+		// Arrange
+		origEnv := New().SetDefault("PATH", "/bin").Apply()
+		defer origEnv.Apply()
+		before := Capture()
+
+		// Act
+		revert, err := CleanRoom("PATH").ApplyE()
+		after := Capture()
+		revert.Apply()
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		Expect(after.keys()).To(Equal([]string{"PATH"}))
+		Expect(Capture()).To(Equal(before))
+	})
+
+	It("should plan the changes", func() {
+		// Act
+		plan := CleanRoom("HOME", "PATH", "LC_ALL").planFor(base)
+
+		// Assert
+		Expect(plan.String()).To(Equal("- AWS_PROFILE=dev\n- AWS_REGION=us-east-1"))
+	})
+
+	It("should validate glob patterns", func() {
+		// Act
+		_, err := New().UnsetMatching("[").ApplyE()
+		_, cleanErr := CleanRoom("PATH", "[").ApplyE()
+
+		// Assert
+		Expect(err).To(MatchError(ErrApplyFailure))
+		Expect(err.Error()).To(ContainSubstring(`pattern "[": syntax error in pattern`))
+		Expect(cleanErr).To(MatchError(ErrApplyFailure))
+	})
+
+	It("should describe the entries", func() {
+		Expect(CleanRoom("PATH", "HOME").UnsetMatching("AWS_*").UnsetMatchingRegexp(regexp.MustCompile("^X")).String()).
+			To(Equal("[clean room keeping [PATH, HOME], unset matching AWS_*, unset matching /^X/]"))
+	})
+})
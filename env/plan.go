@@ -0,0 +1,163 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Describes the effect that applying a Setup entry would have on a variable.
+type ChangeKind int
+
+const (
+	// The variable would be left as it is (e.g. set to its current value, or unset when it is not
+	// set).
+	ChangeNone ChangeKind = iota
+	// The variable would be created.
+	ChangeAdd
+	// The variable would be given a new value.
+	ChangeUpdate
+	// The variable would be removed.
+	ChangeRemove
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeNone:
+		return "none"
+	case ChangeAdd:
+		return "add"
+	case ChangeUpdate:
+		return "update"
+	case ChangeRemove:
+		return "remove"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// A single change to a variable, as described by a Plan.
+type Change struct {
+	Kind ChangeKind
+	Key  string
+	Old  string // the value before the change (empty for ChangeAdd, or when the variable is not set)
+	New  string // the value after the change (empty for ChangeRemove, or when the variable is not set)
+
+	// The values were marked as secret (see Setup.SetSecret).  Values are also treated as secret
+	// when the key matches SecretKeyPatterns.
+	Secret bool
+}
+
+// Renders the change as a line in a diff, redacting secret values: "+ KEY=new" for an addition,
+// "~ KEY=old -> new" for an update, "- KEY=old" for a removal and "  KEY=value" for no change.
+func (c Change) String() string {
+	oldValue := redact(c.Key, c.Old, c.Secret)
+	newValue := redact(c.Key, c.New, c.Secret)
+	switch c.Kind {
+	case ChangeAdd:
+		return fmt.Sprintf("+ %s=%s", c.Key, newValue)
+	case ChangeUpdate:
+		return fmt.Sprintf("~ %s=%s -> %s", c.Key, oldValue, newValue)
+	case ChangeRemove:
+		return fmt.Sprintf("- %s=%s", c.Key, oldValue)
+	}
+	if c.New == "" && c.Old == "" {
+		return fmt.Sprintf("  %s", c.Key)
+	}
+	return fmt.Sprintf("  %s=%s", c.Key, newValue)
+}
+
+// The list of changes that applying a Setup would make, in the order they would be made.
+type Plan []Change
+
+// Returns the changes that applying the Setup to the process environment would make, without
+// modifying the environment.
+//
+// For example, to log what will change before sanitizing the environment:
+//
+//	log.Printf("environment changes:\n%v", setup.Plan())
+//	setup.Apply()
+func (a Setup) Plan() Plan {
+	return a.planFor(OSSource)
+}
+
+func (a Setup) planFor(base Source) Plan {
+	recorder := &recordingEnvironment{virtualEnvironment: newVirtualEnvironment(base), secretKeys: map[string]bool{}}
+	for _, applicator := range a {
+		if secret, ok := applicator.(interface{ isSecret() bool }); ok && secret.isSecret() {
+			for _, key := range applicator.keys() {
+				recorder.secretKeys[key] = true
+			}
+		}
+		if rename, ok := applicator.(*renameEnv); ok && recorder.secretKeys[rename.from] {
+			// e.g. a secret set by an earlier Setup that this one was appended to
+			recorder.secretKeys[rename.to] = true
+		}
+		_, _ = applicator.apply(recorder)
+	}
+	return recorder.changes
+}
+
+// Returns only the changes that would modify the environment (i.e. without ChangeNone entries).
+func (p Plan) Changes() Plan {
+	result := Plan{}
+	for _, change := range p {
+		if change.Kind != ChangeNone {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// Renders the plan like a diff, one change per line (see Change.String).  Entries that would not
+// change anything are omitted.
+func (p Plan) String() string {
+	return fmt.Sprintf("%v", p)
+}
+
+// Implements fmt.Formatter: the 's' and 'v' verbs render the plan like a diff, omitting entries
+// that would not change anything unless the '+' flag is used (e.g. "%+v").
+func (p Plan) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 's', 'v':
+		changes := p
+		if !f.Flag('+') {
+			changes = p.Changes()
+		}
+		lines := make([]string, len(changes))
+		for index, change := range changes {
+			lines[index] = change.String()
+		}
+		fmt.Fprint(f, strings.Join(lines, "\n"))
+	default:
+		fmt.Fprintf(f, "%%!%c(env.Plan=%d changes)", verb, len(p))
+	}
+}
+
+// a virtual environment that records each change made to it
+type recordingEnvironment struct {
+	*virtualEnvironment
+	changes    Plan
+	secretKeys map[string]bool // once a key has been given a secret value, all of its changes are secret
+}
+
+func (e *recordingEnvironment) set(key string, value string) error {
+	change := Change{Kind: ChangeAdd, Key: key, New: value, Secret: e.secretKeys[key]}
+	if old, ok := e.lookup(key); ok {
+		change.Old = old
+		change.Kind = ChangeUpdate
+		if old == value {
+			change.Kind = ChangeNone
+		}
+	}
+	e.changes = append(e.changes, change)
+	return e.virtualEnvironment.set(key, value)
+}
+
+func (e *recordingEnvironment) unset(key string) error {
+	change := Change{Kind: ChangeNone, Key: key, Secret: e.secretKeys[key]}
+	if old, ok := e.lookup(key); ok {
+		change.Old = old
+		change.Kind = ChangeRemove
+	}
+	e.changes = append(e.changes, change)
+	return e.virtualEnvironment.unset(key)
+}
@@ -0,0 +1,88 @@
+package env
+
+import (
+	"fmt"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plan", func() {
+	DescribeTable("planFor",
+		func(setup Setup, expected Plan) {
+			// Act
+			actual := setup.planFor(MapSource{"FOO": "foo", "BAR": "bar"})
+
+			// Assert
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("empty setup", New(), nil),
+		Entry("add", New().Set("NEW", "new"), Plan{{Kind: ChangeAdd, Key: "NEW", New: "new"}}),
+		Entry("update", New().Set("FOO", "fu"), Plan{{Kind: ChangeUpdate, Key: "FOO", Old: "foo", New: "fu"}}),
+		Entry("remove", New().Unset("FOO"), Plan{{Kind: ChangeRemove, Key: "FOO", Old: "foo"}}),
+		Entry("set to same value", New().Set("FOO", "foo"), Plan{{Kind: ChangeNone, Key: "FOO", Old: "foo", New: "foo"}}),
+		Entry("unset missing", New().Unset("NEW"), Plan{{Kind: ChangeNone, Key: "NEW"}}),
+		Entry("changes are cumulative", New().Set("NEW", "1").Set("NEW", "2").Unset("NEW"), Plan{
+			{Kind: ChangeAdd, Key: "NEW", New: "1"},
+			{Kind: ChangeUpdate, Key: "NEW", Old: "1", New: "2"},
+			{Kind: ChangeRemove, Key: "NEW", Old: "2"},
+		}),
+	)
+
+	It("should not modify the process environment", func() {
+		// Arrange
+		name := "__test_plan_env_var__"
+		origEnv := New().Set(name, "original").Apply()
+		defer origEnv.Apply()
+
+		// Act
+		plan := New().Unset(name).Plan()
+
+		// Assert
+		Expect(plan).To(Equal(Plan{{Kind: ChangeRemove, Key: name, Old: "original"}}))
+		Expect(os.Getenv(name)).To(Equal("original"))
+	})
+
+	Context("rendering", func() {
+		plan := Plan{
+			{Kind: ChangeAdd, Key: "NEW", New: "new"},
+			{Kind: ChangeNone, Key: "SAME", Old: "same", New: "same"},
+			{Kind: ChangeUpdate, Key: "FOO", Old: "foo", New: "fu"},
+			{Kind: ChangeNone, Key: "MISSING"},
+			{Kind: ChangeRemove, Key: "BAR", Old: "bar"},
+		}
+
+		It("String omits entries that change nothing", func() {
+			Expect(plan.String()).To(Equal("+ NEW=new\n~ FOO=foo -> fu\n- BAR=bar"))
+		})
+
+		It("formats with %s and %v", func() {
+			Expect(fmt.Sprintf("%s", plan)).To(Equal(plan.String()))
+			Expect(fmt.Sprintf("%v", plan)).To(Equal(plan.String()))
+		})
+
+		It("formats every entry with %+v", func() {
+			Expect(fmt.Sprintf("%+v", plan)).To(Equal("+ NEW=new\n  SAME=same\n~ FOO=foo -> fu\n  MISSING\n- BAR=bar"))
+		})
+
+		It("reports unsupported verbs", func() {
+			Expect(fmt.Sprintf("%d", plan)).To(Equal("%!d(env.Plan=5 changes)"))
+		})
+
+		It("renders an empty plan as empty", func() {
+			Expect(Plan{}.String()).To(BeEmpty())
+		})
+	})
+
+	DescribeTable("ChangeKind.String",
+		func(kind ChangeKind, expected string) {
+			Expect(kind.String()).To(Equal(expected))
+		},
+		Entry("none", ChangeNone, "none"),
+		Entry("add", ChangeAdd, "add"),
+		Entry("update", ChangeUpdate, "update"),
+		Entry("remove", ChangeRemove, "remove"),
+		Entry("unknown", ChangeKind(42), "ChangeKind(42)"),
+	)
+})
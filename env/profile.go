@@ -0,0 +1,277 @@
+package env
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrProfileFailure  = errors.New("failed to load env profile")
+	ErrNotSerializable = errors.New("env setup entry cannot be serialized")
+)
+
+// Reads the named Setup from a profile file, which maps profile names to Setups in JSON (".json")
+// or YAML (".yaml" or ".yml") format.  For example:
+//
+//	ci:
+//	  - {op: set, key: LOG_LEVEL, value: debug}
+//	  - {op: unset, key: HTTP_PROXY}
+//	local-docker:
+//	  - {op: default, key: DOCKER_HOST, value: unix:///var/run/docker.sock}
+//	  - {op: prepend, key: PATH, value: /opt/docker/bin}
+//
+// See Setup.MarshalJSON for the format of each entry.
+func LoadProfile(path string, name string) (Setup, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- reading the caller's file is the point
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := map[string]Setup{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &profiles)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &profiles)
+	default:
+		err = fmt.Errorf("unsupported file type %q", ext)
+	}
+	if err != nil {
+		return nil, profileError(path, err)
+	}
+
+	setup, ok := profiles[name]
+	if !ok {
+		found := strings.Join(slices.Sorted(maps.Keys(profiles)), ", ")
+		return nil, profileError(path, fmt.Errorf("no profile %q (found %s)", name, found))
+	}
+	return setup, nil
+}
+
+func profileError(path string, err error) error {
+	if !errors.Is(err, ErrProfileFailure) {
+		err = fmt.Errorf("%w: %w", ErrProfileFailure, err)
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// Encodes the Setup as a JSON array with one object per entry, in order.  Each object has an "op"
+// and the fields that the op needs:
+//
+//	{"op": "set", "key": "FOO", "value": "foo"}            // Set; "secret": true for SetSecret
+//	{"op": "unset", "key": "FOO"}                          // Unset
+//	{"op": "default", "key": "FOO", "value": "foo"}        // SetDefault
+//	{"op": "prepend", "key": "PATH", "value": "/opt/bin"}  // also "append" and "remove"; "sep"
+//	                                                       // is omitted for ListSeparator
+//	{"op": "rename", "key": "FROM", "to": "TO"}            // Rename
+//	{"op": "unset_matching", "pattern": "AWS_*"}           // UnsetMatching
+//	{"op": "unset_matching", "regexp": "^AWS_"}            // UnsetMatchingRegexp
+//	{"op": "clean_room", "allow": ["PATH", "HOME"]}        // CleanRoom
+//	{"op": "chdir", "value": "testdata"}                   // Chdir
+//	{"op": "time_zone", "value": "UTC"}                    // TimeZone
+//	{"op": "umask", "value": "077"}                        // Umask, in octal
+//	{"op": "write_file", "path": "a.yaml", "value": "...", "mode": "0600"}  // WriteFile
+//	{"op": "set_file", "key": "CREDS", "value": "...", "mode": "0600"}      // SetFile
+//
+// The pattern ops also have a "prefix" if they came from WithPrefix.
+//
+// Secret values are written in full; redaction only applies to text rendered for humans.
+//
+// Returns an error (matching ErrNotSerializable) if the Setup has entries that cannot be
+// represented as data, such as SetFunc.
+func (a Setup) MarshalJSON() ([]byte, error) {
+	entries, err := a.profileEntries()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(entries)
+}
+
+// Decodes a Setup from the JSON format described by MarshalJSON, replacing the receiver.
+//
+// Returns an error (matching ErrProfileFailure) if an entry has an unknown op or is invalid.
+func (a *Setup) UnmarshalJSON(data []byte) error {
+	entries := []profileEntry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	return a.fromProfileEntries(entries)
+}
+
+// Encodes the Setup as a YAML sequence, using the same fields as MarshalJSON.
+func (a Setup) MarshalYAML() (interface{}, error) {
+	return a.profileEntries()
+}
+
+// Decodes a Setup from the YAML format described by MarshalYAML, replacing the receiver.
+func (a *Setup) UnmarshalYAML(node *yaml.Node) error {
+	entries := []profileEntry{}
+	if err := node.Decode(&entries); err != nil {
+		return err
+	}
+	return a.fromProfileEntries(entries)
+}
+
+type profileEntry struct {
+	Op      string   `json:"op" yaml:"op"`
+	Key     string   `json:"key,omitempty" yaml:"key,omitempty"`
+	Value   *string  `json:"value,omitempty" yaml:"value,omitempty"`
+	Secret  bool     `json:"secret,omitempty" yaml:"secret,omitempty"`
+	Sep     *string  `json:"sep,omitempty" yaml:"sep,omitempty"`
+	To      string   `json:"to,omitempty" yaml:"to,omitempty"`
+	Pattern string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Regexp  string   `json:"regexp,omitempty" yaml:"regexp,omitempty"`
+	Allow   []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	Prefix  string   `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Path    string   `json:"path,omitempty" yaml:"path,omitempty"`
+	Mode    string   `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+func (a Setup) profileEntries() ([]profileEntry, error) {
+	entries := []profileEntry{}
+	for _, item := range a {
+		switch entry := item.(type) {
+		case Setup:
+			nested, err := entry.profileEntries()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, nested...)
+		case *addOrUpdateEnv:
+			entries = append(entries, profileEntry{Op: "set", Key: entry.key, Value: &entry.value, Secret: entry.secret})
+		case *removeEnv:
+			entries = append(entries, profileEntry{Op: "unset", Key: entry.key})
+		case *defaultEnv:
+			entries = append(entries, profileEntry{Op: "default", Key: entry.key, Value: &entry.value})
+		case *listEnv:
+			profile := profileEntry{Op: entry.op.String(), Key: entry.key, Value: &entry.value}
+			if entry.sep != ListSeparator {
+				profile.Sep = &entry.sep
+			}
+			entries = append(entries, profile)
+		case *renameEnv:
+			entries = append(entries, profileEntry{Op: "rename", Key: entry.from, To: entry.to})
+		case *unsetMatchingEnv:
+			profile := profileEntry{Op: "unset_matching", Pattern: entry.pattern, Prefix: entry.prefix}
+			if entry.re != nil {
+				profile.Pattern = ""
+				profile.Regexp = entry.re.String()
+			}
+			entries = append(entries, profile)
+		case *cleanRoomEnv:
+			entries = append(entries, profileEntry{Op: "clean_room", Allow: entry.allowlist, Prefix: entry.prefix})
+		case *chdirEnv:
+			entries = append(entries, profileEntry{Op: "chdir", Value: &entry.dir})
+		case *timeZoneEnv:
+			entries = append(entries, profileEntry{Op: "time_zone", Value: &entry.name})
+		case *umaskEnv:
+			mask := fmt.Sprintf("%#o", uint32(entry.mask))
+			entries = append(entries, profileEntry{Op: "umask", Value: &mask})
+		case *fileEnv:
+			mode := fmt.Sprintf("%#o", uint32(entry.mode))
+			entries = append(entries, profileEntry{Op: "write_file", Path: entry.path, Value: &entry.content, Mode: mode})
+		case *setFileEnv:
+			mode := fmt.Sprintf("%#o", uint32(entry.file.mode))
+			entries = append(entries, profileEntry{Op: "set_file", Key: entry.key, Value: &entry.file.content, Mode: mode})
+		case *guardRelease:
+			// only meaningful to the process that applied the Setup
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrNotSerializable, item)
+		}
+	}
+	return entries, nil
+}
+
+func (a *Setup) fromProfileEntries(entries []profileEntry) error {
+	result := New()
+	for index, entry := range entries {
+		value := ""
+		if entry.Value != nil {
+			value = *entry.Value
+		}
+		sep := ListSeparator
+		if entry.Sep != nil {
+			sep = *entry.Sep
+		}
+
+		switch entry.Op {
+		case "set":
+			if entry.Secret {
+				result = result.SetSecret(entry.Key, value)
+			} else {
+				result = result.Set(entry.Key, value)
+			}
+		case "unset":
+			result = result.Unset(entry.Key)
+		case "default":
+			result = result.SetDefault(entry.Key, value)
+		case "prepend":
+			result = result.PrependSep(entry.Key, sep, value)
+		case "append":
+			result = result.AppendSep(entry.Key, sep, value)
+		case "remove":
+			result = result.RemoveSep(entry.Key, sep, value)
+		case "rename":
+			result = result.Rename(entry.Key, entry.To)
+		case "unset_matching":
+			matching := &unsetMatchingEnv{pattern: entry.Pattern, prefix: entry.Prefix}
+			if entry.Regexp != "" {
+				re, err := regexp.Compile(entry.Regexp)
+				if err != nil {
+					return fmt.Errorf("%w: entry %d: %w", ErrProfileFailure, index, err)
+				}
+				matching.re = re
+			}
+			result = append(result, matching)
+		case "clean_room":
+			result = append(result, &cleanRoomEnv{allowlist: slices.Clone(entry.Allow), prefix: entry.Prefix})
+		case "chdir":
+			result = result.Chdir(value)
+		case "time_zone":
+			result = result.TimeZone(value)
+		case "umask":
+			mask, err := parseMode(value)
+			if err != nil {
+				return fmt.Errorf("%w: entry %d: umask %w", ErrProfileFailure, index, err)
+			}
+			result = result.Umask(mask)
+		case "write_file", "set_file":
+			mode, err := parseMode(entry.Mode)
+			if err != nil {
+				return fmt.Errorf("%w: entry %d: mode %w", ErrProfileFailure, index, err)
+			}
+			if entry.Op == "write_file" {
+				result = result.WriteFile(entry.Path, value, mode)
+			} else {
+				result = result.SetFile(entry.Key, value, mode)
+			}
+		default:
+			return fmt.Errorf("%w: entry %d: unknown op %q", ErrProfileFailure, index, entry.Op)
+		}
+
+		if err := result[len(result)-1].validate(); err != nil {
+			return fmt.Errorf("%w: entry %d: %w", ErrProfileFailure, index, err)
+		}
+	}
+	*a = result
+	return nil
+}
+
+func parseMode(value string) (fs.FileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an octal number", value)
+	}
+	return fs.FileMode(mode), nil
+}
@@ -0,0 +1,170 @@
+package env
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+var _ = Describe("Profile", func() {
+	DescribeTable("round trip",
+		func(input Setup, expectedJSON string) {
+			// Act
+			data, err := json.Marshal(input)
+			Expect(err).ToNot(HaveOccurred())
+			fromJSON := Setup{}
+			jsonErr := json.Unmarshal(data, &fromJSON)
+			yamlData, err := yaml.Marshal(input)
+			Expect(err).ToNot(HaveOccurred())
+			fromYAML := Setup{}
+			yamlErr := yaml.Unmarshal(yamlData, &fromYAML)
+
+			// Assert
+			Expect(string(data)).To(MatchJSON(expectedJSON))
+			Expect(jsonErr).ToNot(HaveOccurred())
+			Expect(fromJSON).To(Equal(input))
+			Expect(yamlErr).ToNot(HaveOccurred())
+			Expect(fromYAML).To(Equal(input))
+		},
+		Entry("empty", New(), `[]`),
+		Entry("set and unset", New().Set("FOO", "foo").Unset("BAR").Set("EMPTY", ""),
+			`[{"op":"set","key":"FOO","value":"foo"},{"op":"unset","key":"BAR"},{"op":"set","key":"EMPTY","value":""}]`),
+		Entry("secret", New().SetSecret("PASS", "hunter2"), `[{"op":"set","key":"PASS","value":"hunter2","secret":true}]`),
+		Entry("default", New().SetDefault("FOO", "foo"), `[{"op":"default","key":"FOO","value":"foo"}]`),
+		Entry("lists", New().Prepend("PATH", "/a").Append("PATH", "/b").RemoveSep("FLAGS", " ", "-v"),
+			`[{"op":"prepend","key":"PATH","value":"/a"},{"op":"append","key":"PATH","value":"/b"},
+			  {"op":"remove","key":"FLAGS","value":"-v","sep":" "}]`),
+		Entry("rename", New().Rename("FROM", "TO"), `[{"op":"rename","key":"FROM","to":"TO"}]`),
+		Entry("patterns", New().UnsetMatching("AWS_*").UnsetMatchingRegexp(regexp.MustCompile("^GCP_")),
+			`[{"op":"unset_matching","pattern":"AWS_*"},{"op":"unset_matching","regexp":"^GCP_"}]`),
+		Entry("clean room", CleanRoom("PATH", "LC_*").Set("FOO", "foo"),
+			`[{"op":"clean_room","allow":["PATH","LC_*"]},{"op":"set","key":"FOO","value":"foo"}]`),
+	)
+
+	It("flattens reverts", func() {
+		// Arrange
+		origEnv := New().Set("__test_profile_a__", "old").Unset("__test_profile_b__").Apply()
+		defer origEnv.Apply()
+		revert := New().Set("__test_profile_a__", "new").Set("__test_profile_b__", "b").Apply()
+		defer revert.Apply()
+
+		// Act
+		data, err := json.Marshal(revert)
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(MatchJSON(
+			`[{"op":"set","key":"__test_profile_a__","value":"old"},{"op":"unset","key":"__test_profile_b__"}]`))
+	})
+
+	It("rejects entries that cannot be serialized", func() {
+		// Act
+		_, err := json.Marshal(New().Set("A", "a").SetFunc("B", func(string, bool) (string, bool) { return "", false }))
+		_, yamlErr := yaml.Marshal(New().SetFunc("B", func(string, bool) (string, bool) { return "", false }))
+
+		// Assert
+		Expect(err).To(MatchError(ErrNotSerializable))
+		Expect(err.Error()).To(ContainSubstring("compute B"))
+		Expect(yamlErr).To(MatchError(ErrNotSerializable))
+	})
+
+	DescribeTable("decoding errors",
+		func(input string, expectedMsg string) {
+			// Act
+			actual := New().Set("KEEP", "me")
+			err := json.Unmarshal([]byte(input), &actual)
+
+			// Assert
+			Expect(err).To(MatchError(ErrProfileFailure))
+			Expect(err.Error()).To(ContainSubstring(expectedMsg))
+			Expect(actual).To(Equal(New().Set("KEEP", "me")))
+		},
+		Entry("unknown op", `[{"op":"set","key":"A"},{"op":"frobnicate"}]`, `entry 1: unknown op "frobnicate"`),
+		Entry("missing op", `[{"key":"A"}]`, `entry 0: unknown op ""`),
+		Entry("invalid key", `[{"op":"unset","key":"A=B"}]`, `entry 0: key "A=B" contains '='`),
+		Entry("invalid pattern", `[{"op":"clean_room","allow":["["]}]`, `entry 0: pattern "["`),
+		Entry("invalid regexp", `[{"op":"unset_matching","regexp":"("}]`, "entry 0: error parsing regexp"),
+	)
+
+	Context("LoadProfile", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+		})
+
+		write := func(name string, content string) string {
+			path := filepath.Join(dir, name)
+			Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+			return path
+		}
+
+		DescribeTable("loads the named profile",
+			func(name string, content string) {
+				// Arrange
+				path := write(name, content)
+
+				// Act
+				actual, err := LoadProfile(path, "ci")
+
+				// Assert
+				Expect(err).ToNot(HaveOccurred())
+				Expect(actual).To(Equal(New().Set("LOG_LEVEL", "debug").Unset("HTTP_PROXY").Prepend("PATH", "/opt/bin")))
+			},
+			Entry("json", "profiles.json", `{
+				"local": [{"op": "set", "key": "LOG_LEVEL", "value": "info"}],
+				"ci": [
+					{"op": "set", "key": "LOG_LEVEL", "value": "debug"},
+					{"op": "unset", "key": "HTTP_PROXY"},
+					{"op": "prepend", "key": "PATH", "value": "/opt/bin"}
+				]
+			}`),
+			Entry("yaml", "profiles.yaml", `
+local:
+  - {op: set, key: LOG_LEVEL, value: info}
+ci:
+  - {op: set, key: LOG_LEVEL, value: debug}
+  - {op: unset, key: HTTP_PROXY}
+  - op: prepend
+    key: PATH
+    value: /opt/bin
+`),
+			Entry("yml", "profiles.YML", "ci: [{op: set, key: LOG_LEVEL, value: debug}, {op: unset, key: HTTP_PROXY}, "+
+				"{op: prepend, key: PATH, value: /opt/bin}]"),
+		)
+
+		DescribeTable("errors",
+			func(name string, content string, expectedMsg string) {
+				// Arrange
+				path := write(name, content)
+
+				// Act
+				actual, err := LoadProfile(path, "ci")
+
+				// Assert
+				Expect(actual).To(BeNil())
+				Expect(err).To(MatchError(ErrProfileFailure))
+				Expect(err.Error()).To(HavePrefix(path + ": failed to load env profile: "))
+				Expect(err.Error()).To(ContainSubstring(expectedMsg))
+			},
+			Entry("unsupported extension", "profiles.toml", "", `unsupported file type ".toml"`),
+			Entry("missing profile", "profiles.json", `{"local": [], "docker": []}`, `no profile "ci" (found docker, local)`),
+			Entry("invalid json", "profiles.json", `{"ci": [`, "unexpected end of JSON input"),
+			Entry("invalid yaml", "profiles.yaml", "ci: {op: set}", "cannot unmarshal"),
+			Entry("invalid entry", "profiles.yaml", "ci: [{op: nope}]", `entry 0: unknown op "nope"`),
+		)
+
+		It("reports missing files", func() {
+			// Act
+			_, err := LoadProfile(filepath.Join(dir, "missing.json"), "ci")
+
+			// Assert
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+	})
+})
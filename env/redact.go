@@ -0,0 +1,75 @@
+package env
+
+import (
+	"path"
+	"strings"
+)
+
+var (
+	// Key patterns (see path.Match) for variables whose values are secret.  Matching ignores case.
+	//
+	// Secret values are replaced with RedactedValue in all human-readable output produced by this
+	// package (Setup and Plan rendering, and error messages).  You can also mark individual
+	// entries as secret with Setup.SetSecret.
+	//
+	// You can change this in your main() function (or test suite setup) to suit your environment.
+	SecretKeyPatterns = []string{"*_TOKEN", "*_PASSWORD", "*_SECRET", "*_API_KEY"}
+
+	// Replaces secret values in human-readable output.
+	RedactedValue = "******"
+)
+
+// Reports whether the variable's value should be redacted, according to SecretKeyPatterns.
+func IsSecretKey(key string) bool {
+	upperKey := strings.ToUpper(key)
+	for _, pattern := range SecretKeyPatterns {
+		if matched, _ := path.Match(strings.ToUpper(pattern), upperKey); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the value for display, redacting it if required
+func redact(key string, value string, secret bool) string {
+	if value != "" && (secret || IsSecretKey(key)) {
+		return RedactedValue
+	}
+	return value
+}
+
+// wraps an error whose message may contain a secret value, masking the value in Error() while
+// preserving the error chain for errors.Is/errors.As
+type redactedError struct {
+	err    error
+	secret string
+}
+
+func redactError(err error, key string, value string, secret bool) error {
+	if err == nil || redact(key, value, secret) != RedactedValue {
+		return err
+	}
+	return &redactedError{err: err, secret: value}
+}
+
+func (e *redactedError) Error() string {
+	return strings.ReplaceAll(e.err.Error(), e.secret, RedactedValue)
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// hides the whole message of an error about a secret value, which may quote any part of the value
+// (e.g. one element of a list), preserving the error chain for errors.Is/errors.As
+type maskedError struct {
+	err error
+}
+
+func (e *maskedError) Error() string {
+	return "invalid value " + RedactedValue
+}
+
+func (e *maskedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,168 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redaction", func() {
+	DescribeTable("IsSecretKey",
+		func(key string, expected bool) {
+			Expect(IsSecretKey(key)).To(Equal(expected))
+		},
+		Entry("token", "GITHUB_TOKEN", true),
+		Entry("password", "DB_PASSWORD", true),
+		Entry("secret", "CLIENT_SECRET", true),
+		Entry("api key", "STRIPE_API_KEY", true),
+		Entry("ignores case", "db_Password", true),
+		Entry("plain key", "HOME", false),
+		Entry("pattern is anchored", "TOKEN_COUNT", false),
+	)
+
+	It("uses the configured patterns", func() {
+		// Arrange
+		orig := SecretKeyPatterns
+		DeferCleanup(func() { SecretKeyPatterns = orig })
+
+		// Act
+		SecretKeyPatterns = []string{"PRIVATE_*"}
+
+		// Assert
+		Expect(IsSecretKey("PRIVATE_THING")).To(BeTrue())
+		Expect(IsSecretKey("GITHUB_TOKEN")).To(BeFalse())
+	})
+
+	Context("Setup.String", func() {
+		It("describes the entries", func() {
+			Expect(New().Set("FOO", "foo").Unset("BAR").String()).To(Equal("[set FOO=foo, unset BAR]"))
+			Expect(fmt.Sprintf("%v", New().Set("FOO", 1))).To(Equal("[set FOO=1]"))
+			Expect(New().String()).To(Equal("[]"))
+		})
+
+		It("redacts secret values", func() {
+			// Act
+			actual := New().Set("GITHUB_TOKEN", "ghp_123").SetSecret("DSN", "postgres://u:p@host").Set("FOO", "foo").String()
+
+			// Assert
+			Expect(actual).To(Equal("[set GITHUB_TOKEN=******, set DSN=******, set FOO=foo]"))
+		})
+	})
+
+	Context("Plan", func() {
+		It("redacts secret values", func() {
+			// Arrange
+			setup := New().SetSecret("DSN", "new-dsn").Set("API_TOKEN", "new-token").Set("FOO", "foo").Unset("DSN")
+
+			// Act
+			actual := setup.planFor(MapSource{"DSN": "old-dsn", "API_TOKEN": "old-token"}).String()
+
+			// Assert
+			Expect(actual).To(Equal("~ DSN=****** -> ******\n~ API_TOKEN=****** -> ******\n+ FOO=foo\n- DSN=******"))
+		})
+
+		It("redacts values that are renamed from secrets", func() {
+			// Arrange
+			marked := New().SetSecret("X", "hunter2").Rename("X", "Y")
+			appended := append(New().SetSecret("X", "hunter2"), New().Rename("X", "Y")...)
+			matched := New().Rename("GH_TOKEN", "GH")
+
+			// Act
+			markedPlan := marked.planFor(MapSource{}).String()
+			appendedPlan := appended.planFor(MapSource{}).String()
+			matchedPlan := matched.planFor(MapSource{"GH_TOKEN": "ghp_123"}).String()
+
+			// Assert
+			Expect(markedPlan).To(Equal("+ X=******\n+ Y=******\n- X=******"))
+			Expect(appendedPlan).To(Equal(markedPlan))
+			Expect(matchedPlan).To(Equal("+ GH=******\n- GH_TOKEN=******"))
+		})
+
+		It("keeps the secret marking when a secret is renamed", func() {
+			// Arrange
+			from, to := "__test_redact_rename_from__", "__test_redact_rename_to__"
+			origEnv := New().Unset(from).Set(to, "before").Apply()
+			defer origEnv.Apply()
+
+			// Act
+			revert := New().SetSecret(from, "after").Rename(from, to).Apply()
+			defer revert.Apply()
+
+			// Assert
+			Expect(revert.String()).To(Equal("[unset " + from + ", set " + to + "=******]"))
+		})
+
+		It("keeps the secret marking on the revert", func() {
+			// Arrange
+			name := "__test_redact_revert__"
+			origEnv := New().Set(name, "before").Apply()
+			defer origEnv.Apply()
+
+			// Act
+			revert := New().SetSecret(name, "after").Apply()
+
+			// Assert
+			Expect(revert.String()).To(Equal("[set " + name + "=******]"))
+			Expect(revert.Plan().String()).To(Equal("~ " + name + "=****** -> ******"))
+			revert.Apply()
+		})
+
+		It("keeps the secret marking when a revert is reverted", func() {
+			// Arrange
+			name := "__test_redact_revert_revert__"
+			origEnv := New().Unset(name).Apply()
+			defer origEnv.Apply()
+			revert := New().SetSecret(name, "after").Apply()
+
+			// Act
+			reapply := revert.Apply()
+			defer reapply.Apply()
+
+			// Assert
+			Expect(reapply.String()).To(Equal("[set " + name + "=******]"))
+			Expect(reapply.Plan().String()).To(Equal("+ " + name + "=******"))
+		})
+	})
+
+	Context("redactError", func() {
+		cause := errors.New(`parsing "hunter2": invalid syntax`)
+
+		It("masks the value for secret keys", func() {
+			// Act
+			err := redactError(cause, "DB_PASSWORD", "hunter2", false)
+
+			// Assert
+			Expect(err.Error()).To(Equal(`parsing "******": invalid syntax`))
+			Expect(errors.Is(err, cause)).To(BeTrue())
+		})
+
+		It("masks the value for entries marked as secret", func() {
+			// Act
+			err := redactError(cause, "DSN", "hunter2", true)
+
+			// Assert
+			Expect(err.Error()).To(Equal(`parsing "******": invalid syntax`))
+		})
+
+		It("leaves other errors alone", func() {
+			Expect(redactError(cause, "DB_HOST", "hunter2", false)).To(BeIdenticalTo(cause))
+			Expect(redactError(cause, "DB_PASSWORD", "", false)).To(BeIdenticalTo(cause))
+			Expect(redactError(nil, "DB_PASSWORD", "hunter2", true)).To(BeNil())
+		})
+
+		It("masks secret values in apply errors", func() {
+			// Act
+			_, err := New().SetSecret("DSN", "postgres://u:p@host").applyAtomically(&failingEnvironment{
+				virtualEnvironment: newVirtualEnvironment(MapSource{}),
+				failKey:            "DSN",
+			})
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(err.Error()).To(ContainSubstring(`value "******"`))
+			Expect(err.Error()).ToNot(ContainSubstring("postgres"))
+		})
+	})
+})
@@ -1,8 +1,15 @@
 package env
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrApplyFailure = errors.New("failed to apply env setup")
 )
 
 // Provides a means for configuring, applying, and reverting a specific set of environment variables
@@ -36,6 +43,14 @@ func (a Setup) Set(key string, value interface{}) Setup {
 	return append(a, &addOrUpdateEnv{key: key, value: fmt.Sprint(value)})
 }
 
+// Adds an entry like Set, but marks the value as secret so that it is redacted whenever the Setup
+// (or a Plan for it) is rendered as text.
+//
+// Variables whose keys match SecretKeyPatterns are redacted even when added with Set.
+func (a Setup) SetSecret(key string, value interface{}) Setup {
+	return append(a, &addOrUpdateEnv{key: key, value: fmt.Sprint(value), secret: true})
+}
+
 // Adds an entry that will remove an environment variable (if it exists).
 func (a Setup) Unset(key string) Setup {
 	return append(a, &removeEnv{key: key})
@@ -43,46 +58,279 @@ func (a Setup) Unset(key string) Setup {
 
 // Applies the environment (adding, updating, and removing variables) and returns a new
 // Setup that will revert your environment back to its starting state.
+//
+// Apply makes a best effort: entries that cannot be applied (e.g. because the key is invalid) are
+// ignored.  Use ApplyE if you need to know about failures.
 func (a Setup) Apply() Setup {
+	guard := beginGuard(a)
+	inverters, _ := a.applyTo(processEnvironment{})
+	return guard.commit(inverters)
+}
+
+// Applies the environment like Apply, but stops at the first entry that cannot be applied.
+//
+// All keys are validated before any changes are made, and if an entry fails to apply then the
+// entries already applied are rolled back, so that the environment is either fully modified or
+// left untouched.
+//
+// The returned error names each key that failed and matches ErrApplyFailure.  The Setup used to
+// revert your environment is only returned on success.
+func (a Setup) ApplyE() (Setup, error) {
+	guard := beginGuard(a)
+	inverters, err := a.applyAtomically(processEnvironment{})
+	if err != nil {
+		guard.abort()
+		return nil, err
+	}
+	return guard.commit(inverters), nil
+}
+
+func (a Setup) applyAtomically(env environment) (Setup, error) {
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+
 	inverters := Setup{}
+	for _, applicator := range a {
+		i, err := applicator.apply(env)
+		inverters = inverters.addInverter(i)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf(errErrFmt, ErrApplyFailure, err), inverters.rollback(env))
+		}
+	}
+	return inverters, nil
+}
+
+// applies every entry, collecting any errors rather than stopping at the first one.
+func (a Setup) applyTo(env environment) (Setup, error) {
+	inverters := Setup{}
+	var errs []error
+
+	for _, applicator := range a {
+		i, err := applicator.apply(env)
+		inverters = inverters.addInverter(i)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return inverters, errors.Join(errs...)
+}
+
+// records an inverter, unless earlier inverters already restore all of its keys (or its process
+// state): the first inverter for a key holds the key's original value, so later ones must not be
+// applied after it.
+func (a Setup) addInverter(inverter applicator) Setup {
+	if inverter == nil {
+		return a
+	}
+	if composite, ok := inverter.(Setup); ok {
+		for _, i := range composite {
+			a = a.addInverter(i)
+		}
+		return a
+	}
+	keys := inverter.keys()
+	if len(keys) > 0 && len(intersect(keys, a.keys())) == len(keys) {
+		return a
+	}
+	if state, ok := inverter.(processState); ok && a.hasState(state.state()) {
+		return a
+	}
+	return append(a, inverter)
+}
+
+func (a Setup) hasState(state string) bool {
+	return slices.ContainsFunc(a, func(inverter applicator) bool {
+		other, ok := inverter.(processState)
+		return ok && other.state() == state
+	})
+}
 
+// allows a Setup to be used as a (composite) applicator
+func (a Setup) apply(env environment) (applicator, error) {
+	return a.applyTo(env)
+}
+
+// reverts inverters in the reverse order in which they were produced.
+func (a Setup) rollback(env environment) error {
+	var errs []error
+	for index := len(a) - 1; index >= 0; index-- {
+		if _, err := a[index].apply(env); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: rollback failed: %w", ErrApplyFailure, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Describes the entries in the Setup, e.g. "[set FOO=foo, unset BAR]".  Secret values are redacted.
+func (a Setup) String() string {
+	entries := make([]string, len(a))
+	for index, applicator := range a {
+		entries[index] = applicator.String()
+	}
+	return "[" + strings.Join(entries, ", ") + "]"
+}
+
+// the keys modified by all entries, in order of first appearance
+func (a Setup) keys() []string {
+	var result []string
 	for _, applicator := range a {
-		if i := applicator.apply(); i != nil {
-			inverters = append(inverters, i)
+		for _, key := range applicator.keys() {
+			if !slices.Contains(result, key) {
+				result = append(result, key)
+			}
 		}
 	}
-	return inverters
+	return result
+}
+
+func (a Setup) validate() error {
+	var errs []error
+	for _, applicator := range a {
+		if err := applicator.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf(errErrFmt, ErrApplyFailure, errors.Join(errs...))
+	}
+	return nil
 }
 
 type applicator interface {
-	apply() applicator
+	// modifies the environment and returns the applicator that will revert the change (or nil if
+	// there is nothing to revert)
+	apply(env environment) (applicator, error)
+
+	// reports problems that would prevent the applicator from being applied
+	validate() error
+
+	// the variables that the applicator may modify
+	keys() []string
+
+	// describes the entry for humans; secret values must be redacted
+	fmt.Stringer
+}
+
+// the target that applicators modify: normally the process environment, but a Setup can also be
+// evaluated against a virtual environment without modifying the process.
+type environment interface {
+	lookup(key string) (string, bool)
+	set(key string, value string) error
+	unset(key string) error
+
+	// lists the variables in the environment
+	names() []string
+}
+
+type processEnvironment struct{}
+
+func (processEnvironment) lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+func (processEnvironment) names() []string {
+	return environKeys(os.Environ())
+}
+
+func (processEnvironment) set(key string, value string) error {
+	if err := os.Setenv(key, value); err != nil {
+		return fmt.Errorf("key %q: %w", key, err)
+	}
+	return nil
+}
+
+func (processEnvironment) unset(key string) error {
+	if err := os.Unsetenv(key); err != nil {
+		return fmt.Errorf("key %q: %w", key, err)
+	}
+	return nil
 }
 
 type addOrUpdateEnv struct {
-	key   string
-	value string
+	key    string
+	value  string
+	secret bool
 }
 
 type removeEnv struct {
-	key string
+	key    string
+	secret bool // the value being removed is a secret (e.g. when reverting SetSecret)
 }
 
-func (a *addOrUpdateEnv) apply() applicator {
+func (a *addOrUpdateEnv) apply(env environment) (applicator, error) {
 	var inverter applicator
-	if value, ok := os.LookupEnv(a.key); ok {
-		inverter = &addOrUpdateEnv{key: a.key, value: value}
+	if value, ok := env.lookup(a.key); ok {
+		inverter = &addOrUpdateEnv{key: a.key, value: value, secret: a.secret}
 	} else {
-		inverter = &removeEnv{key: a.key}
+		inverter = &removeEnv{key: a.key, secret: a.secret}
+	}
+	if err := env.set(a.key, a.value); err != nil {
+		return nil, redactError(err, a.key, a.value, a.secret)
 	}
-	os.Setenv(a.key, a.value)
-	return inverter
+	return inverter, nil
 }
 
-func (a *removeEnv) apply() applicator {
+func (a *addOrUpdateEnv) validate() error {
+	if err := validateKey(a.key); err != nil {
+		return err
+	}
+	if strings.ContainsRune(a.value, 0) {
+		return fmt.Errorf("key %q: value contains NUL", a.key)
+	}
+	return nil
+}
+
+func (a *addOrUpdateEnv) keys() []string {
+	return []string{a.key}
+}
+
+func (a *addOrUpdateEnv) String() string {
+	return fmt.Sprintf("set %s=%s", a.key, redact(a.key, a.value, a.secret))
+}
+
+func (a *addOrUpdateEnv) isSecret() bool {
+	return a.secret
+}
+
+func (a *removeEnv) apply(env environment) (applicator, error) {
 	var inverter applicator = nil
-	if value, ok := os.LookupEnv(a.key); ok {
-		inverter = &addOrUpdateEnv{key: a.key, value: value}
+	if value, ok := env.lookup(a.key); ok {
+		inverter = &addOrUpdateEnv{key: a.key, value: value, secret: a.secret}
+	}
+	if err := env.unset(a.key); err != nil {
+		return nil, err
+	}
+	return inverter, nil
+}
+
+func (a *removeEnv) validate() error {
+	return validateKey(a.key)
+}
+
+func (a *removeEnv) keys() []string {
+	return []string{a.key}
+}
+
+func (a *removeEnv) String() string {
+	return "unset " + a.key
+}
+
+func (a *removeEnv) isSecret() bool {
+	return a.secret
+}
+
+func validateKey(key string) error {
+	switch {
+	case key == "":
+		return errors.New("key is empty")
+	case strings.ContainsRune(key, '='):
+		return fmt.Errorf("key %q contains '='", key)
+	case strings.ContainsRune(key, 0):
+		return fmt.Errorf("key %q contains NUL", key)
 	}
-	os.Unsetenv(a.key)
-	return inverter
+	return nil
 }
@@ -1,6 +1,8 @@
 package env
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -50,6 +52,10 @@ var _ = Describe("VarSetup", func() {
 				New().Unset("foo").Set("junk", "trunk").Set("flim", "flam"),
 				New().Set("foo", "bar").Set("junk", "pile").Unset("flim"),
 			}),
+		Entry("same key more than once",
+			New().Set("flim", "flam"),
+			New().Set("flim", "pool").Unset("flim").Set("flim", "dool"),
+			expectations{New().Set("flim", "flam"), New().Set("flim", "dool")}),
 		Entry("add with integer",
 			New(),
 			New().Set("testint", 100),
@@ -87,4 +93,83 @@ var _ = Describe("VarSetup", func() {
 			Expect(ok).To(BeFalse())
 		})
 	})
+
+	Describe("ApplyE", func() {
+		var (
+			names  = []string{"__test_apply_e_a__", "__test_apply_e_b__"}
+			orig   Setup
+			before Setup
+		)
+		BeforeEach(func() {
+			orig = New().Set(names[0], "original").Unset(names[1]).Apply()
+			before = Capture()
+		})
+		AfterEach(func() {
+			orig.Apply()
+		})
+
+		It("should apply and return the revert setup", func() {
+			// Act
+			revert, err := New().Set(names[0], "changed").Set(names[1], "added").ApplyE()
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(revert).To(Equal(New().Set(names[0], "original").Unset(names[1])))
+			Expect(os.Getenv(names[0])).To(Equal("changed"))
+			Expect(os.Getenv(names[1])).To(Equal("added"))
+		})
+
+		DescribeTable("should validate keys before making changes",
+			func(input Setup, expectedMsgs ...string) {
+				// Act
+				revert, err := input.ApplyE()
+
+				// Assert
+				Expect(revert).To(BeNil())
+				Expect(err).To(MatchError(ErrApplyFailure))
+				for _, msg := range expectedMsgs {
+					Expect(err.Error()).To(ContainSubstring(msg))
+				}
+				Expect(Capture()).To(Equal(before))
+			},
+			Entry("empty key", New().Set(names[0], "changed").Set("", "x"), "key is empty"),
+			Entry("key with '='", New().Set(names[0], "changed").Unset("A=B"), `key "A=B" contains '='`),
+			Entry("key with NUL", New().Set(names[0], "changed").Set("A\x00B", "x"), `key "A\x00B" contains NUL`),
+			Entry("value with NUL", New().Set(names[0], "changed").Set("AB", "x\x00y"), `key "AB": value contains NUL`),
+			Entry("names each bad key", New().Set("A=B", "x").Set(names[0], "changed").Unset("C=D"),
+				`key "A=B" contains '='`, `key "C=D" contains '='`),
+		)
+
+		It("should roll back applied entries when a later entry fails", func() {
+			// Arrange
+			virtual := &failingEnvironment{virtualEnvironment: newVirtualEnvironment(MapSource{"A": "a"}), failKey: "C"}
+			input := New().Set("A", "changed").Set("B", "added").Set("A", "twice").Set("C", "fails").Set("D", "skipped")
+
+			// Act
+			revert, err := input.applyAtomically(virtual)
+
+			// Assert
+			Expect(revert).To(BeNil())
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(err.Error()).To(ContainSubstring(`key "C"`))
+			value, _ := virtual.lookup("A")
+			Expect(value).To(Equal("a"))
+			_, ok := virtual.lookup("B")
+			Expect(ok).To(BeFalse())
+			_, ok = virtual.lookup("D")
+			Expect(ok).To(BeFalse())
+		})
+	})
 })
+
+type failingEnvironment struct {
+	*virtualEnvironment
+	failKey string
+}
+
+func (e *failingEnvironment) set(key string, value string) error {
+	if key == e.failKey {
+		return fmt.Errorf("key %q: value %q: %w", key, value, errors.New("injected failure"))
+	}
+	return e.virtualEnvironment.set(key, value)
+}
@@ -0,0 +1,125 @@
+package env
+
+import (
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Returns a Setup describing every variable in the current process environment.
+//
+// Unlike a Setup built with Set/Unset, a snapshot describes the whole environment, so you can use
+// it with Diff and Restore to undo changes that were not made via a Setup (e.g. direct calls to
+// os.Setenv from a library):
+//
+//	snapshot := env.Capture()
+//	defer snapshot.Restore()
+func Capture() Setup {
+	return captureFrom(os.Environ())
+}
+
+// Returns a Setup that, when applied to an environment matching snapshot 'from', produces an
+// environment matching snapshot 'to'.
+//
+// Variables found in 'from' but not in 'to' are unset; variables that are new or changed in 'to'
+// are set.  Variables that are identical in both snapshots are left alone.
+func Diff(from Setup, to Setup) Setup {
+	fromValues := from.snapshot()
+	toValues := to.snapshot()
+
+	result := New()
+	for _, key := range sortedKeys(fromValues) {
+		if _, ok := toValues[key]; !ok {
+			result = result.Unset(key)
+		}
+	}
+	for _, key := range sortedKeys(toValues) {
+		if value, ok := fromValues[key]; !ok || value != toValues[key] {
+			result = result.Set(key, toValues[key])
+		}
+	}
+	return result
+}
+
+// Treats the Setup as a snapshot and makes the process environment match it exactly, removing any
+// variables that the snapshot does not contain.
+//
+// Returns a Setup that will revert your environment back to its state before the restore.
+func (a Setup) Restore() Setup {
+	return Diff(Capture(), a).Apply()
+}
+
+// Compares the process environment with a snapshot taken earlier by Capture, ignoring variables
+// whose keys match any of the 'ignore' patterns (see path.Match).  Returns the changes made since
+// the snapshot, and a Setup that will undo them.
+//
+// This is the basis of DetectLeaks, which fails tests that do not revert their changes.
+func ChangesSince(snapshot Setup, ignore ...string) (Plan, Setup) {
+	current := Capture()
+	changes := Diff(snapshot, current).withoutMatching(ignore)
+	return changes.planFor(MapSource(snapshot.snapshot())), Diff(current, snapshot).withoutMatching(ignore)
+}
+
+// drops the entries for keys that match any of the patterns
+func (a Setup) withoutMatching(patterns []string) Setup {
+	matcher := &cleanRoomEnv{allowlist: patterns}
+	result := New()
+	for _, entry := range a {
+		if !slices.ContainsFunc(entry.keys(), matcher.allowed) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// reduces the setup to the variables it would leave set when applied to an empty environment.
+func (a Setup) snapshot() map[string]string {
+	virtual := newVirtualEnvironment(nil)
+	_, _ = a.applyTo(virtual)
+
+	values := map[string]string{}
+	for key, value := range virtual.changes {
+		if value != nil {
+			values[key] = *value
+		}
+	}
+	return values
+}
+
+func captureFrom(environ []string) Setup {
+	values := parseEnviron(environ)
+
+	result := New()
+	for _, key := range sortedKeys(values) {
+		result = result.Set(key, values[key])
+	}
+	return result
+}
+
+// lists the variables in an os.Environ()-style slice, sorted
+func environKeys(environ []string) []string {
+	return sortedKeys(parseEnviron(environ))
+}
+
+func parseEnviron(environ []string) map[string]string {
+	values := map[string]string{}
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			// windows keeps some per-drive entries of the form "=C:=C:\foo"; these are not variables
+			continue
+		}
+		values[key] = value
+	}
+	return values
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
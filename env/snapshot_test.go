@@ -0,0 +1,125 @@
+package env
+
+import (
+	"fmt"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot", func() {
+	Context("Capture", func() {
+		It("should capture every variable in the environment", func() {
+			// Arrange
+			origEnv := New().Set("__test_capture_a__", "alpha").Unset("__test_capture_b__").Apply()
+			defer origEnv.Apply()
+
+			// Act
+			snapshot := Capture()
+
+			// Assert
+			Expect(snapshot).To(ContainElement(&addOrUpdateEnv{key: "__test_capture_a__", value: "alpha"}))
+			Expect(snapshot.snapshot()).ToNot(HaveKey("__test_capture_b__"))
+			Expect(snapshot).To(HaveLen(len(os.Environ())))
+		})
+
+		It("should ignore entries that are not variables", func() {
+			// Act
+			snapshot := captureFrom([]string{"=C:=C:\\foo", "B=2", "A=1=one", "junk"})
+
+			// Assert
+			Expect(snapshot).To(Equal(New().Set("A", "1=one").Set("B", "2")))
+		})
+	})
+
+	DescribeTable("Diff",
+		func(from Setup, to Setup, expected Setup) {
+			// Act
+			actual := Diff(from, to)
+
+			// Assert
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("empty snapshots", New(), New(), New()),
+		Entry("identical snapshots", New().Set("A", "1"), New().Set("A", "1"), New()),
+		Entry("added variable", New(), New().Set("A", "1"), New().Set("A", "1")),
+		Entry("removed variable", New().Set("A", "1"), New(), New().Unset("A")),
+		Entry("changed variable", New().Set("A", "1"), New().Set("A", "2"), New().Set("A", "2")),
+		Entry("unset entries are absent", New().Set("A", "1").Unset("B"), New().Unset("A").Set("B", "2"),
+			New().Unset("A").Set("B", "2")),
+		Entry("mixed changes are sorted by key",
+			New().Set("C", "3").Set("A", "1").Set("D", "4"),
+			New().Set("B", "2").Set("A", "10").Set("D", "4"),
+			New().Unset("C").Set("A", "10").Set("B", "2")),
+	)
+
+	Context("Restore", func() {
+		It("should restore the environment exactly", func() {
+			// Arrange
+			name := "__test_restore_env_var__"
+			extra := "__test_restore_extra_var__"
+			origEnv := New().Set(name, "original").Unset(extra).Apply()
+			defer origEnv.Apply()
+			snapshot := Capture()
+
+			os.Setenv(name, "changed")
+			os.Setenv(extra, "leaked")
+
+			// Act
+			revert := snapshot.Restore()
+
+			// Assert
+			value, ok := os.LookupEnv(name)
+			Expect(ok).To(BeTrue())
+			Expect(value).To(Equal("original"))
+			_, ok = os.LookupEnv(extra)
+			Expect(ok).To(BeFalse())
+			Expect(Capture()).To(Equal(snapshot))
+
+			// revert puts the leaked values back
+			revert.Apply()
+			value, _ = os.LookupEnv(name)
+			Expect(value).To(Equal("changed"))
+			value, _ = os.LookupEnv(extra)
+			Expect(value).To(Equal("leaked"))
+			snapshot.Restore()
+		})
+	})
+
+	Context("ChangesSince", func() {
+		It("should report and undo changes since the snapshot", func() {
+			// Arrange
+			name := "__test_changes_since__"
+			secret := "__TEST_CHANGES_SINCE_TOKEN"
+			ignored := "__test_changes_since_ignored__"
+			origEnv := New().Set(name, "original").Unset(secret).Unset(ignored).Apply()
+			defer origEnv.Apply()
+			snapshot := Capture()
+
+			os.Setenv(name, "changed")
+			os.Setenv(secret, "hunter2")
+			os.Setenv(ignored, "changed")
+
+			// Act
+			changes, revert := ChangesSince(snapshot, "*_ignored__")
+
+			// Assert
+			Expect(changes.String()).To(Equal(fmt.Sprintf("+ %s=******\n~ %s=original -> changed", secret, name)))
+			revert.Apply()
+			Expect(os.Getenv(name)).To(Equal("original"))
+			_, ok := os.LookupEnv(secret)
+			Expect(ok).To(BeFalse())
+			Expect(os.Getenv(ignored)).To(Equal("changed"))
+		})
+
+		It("should report nothing when the environment is unchanged", func() {
+			// Act
+			changes, revert := ChangesSince(Capture())
+
+			// Assert
+			Expect(changes).To(BeEmpty())
+			Expect(revert).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,149 @@
+package env
+
+import (
+	"os"
+	"sort"
+)
+
+// Source provides the values of environment variables.
+//
+// Functions that read the environment (such as ResolveEnvWithName) use the process environment by
+// default; supply a different Source to resolve values without reading or modifying global state,
+// e.g. when running tests in parallel.
+//
+// A Source may also provide a `Keys() []string` method listing the variables it holds; this is
+// required to evaluate entries such as UnsetMatching and CleanRoom against the Source without
+// applying them.  The Sources provided by this package all do.
+type Source interface {
+	// Returns the value of the named variable and true, or false if the variable is not set.
+	LookupEnv(key string) (string, bool)
+}
+
+// Adapts a lookup function with the same signature as os.LookupEnv into a Source.
+type SourceFunc func(key string) (string, bool)
+
+func (f SourceFunc) LookupEnv(key string) (string, bool) {
+	return f(key)
+}
+
+// A Source backed by a plain map of variable names to values.
+type MapSource map[string]string
+
+func (m MapSource) LookupEnv(key string) (string, bool) {
+	value, ok := m[key]
+	return value, ok
+}
+
+// Lists the variables in the map, sorted.
+func (m MapSource) Keys() []string {
+	return sortedKeys(m)
+}
+
+var (
+	// The Source for the process environment.
+	OSSource Source = osSource{}
+)
+
+type osSource struct{}
+
+func (osSource) LookupEnv(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+func (osSource) Keys() []string {
+	return environKeys(os.Environ())
+}
+
+// implemented by Sources that can list their variables
+type keyLister interface {
+	Keys() []string
+}
+
+// Returns a Source that presents the environment that would result from applying the Setup on top
+// of 'base', without applying it.
+//
+// The Setup is evaluated whenever a value is looked up, so changes to 'base' are reflected in the
+// result.  A nil 'base' is treated as an empty environment.
+//
+// For example, to resolve a configuration struct without modifying the process environment:
+//
+//	src := env.New().Set("ENV_PORT", 8080).Source(env.OSSource)
+//	err := env.ResolveEnv(&cfg, env.WithSource(src))
+func (a Setup) Source(base Source) Source {
+	return &setupSource{setup: a, base: base}
+}
+
+type setupSource struct {
+	setup Setup
+	base  Source
+}
+
+func (s *setupSource) LookupEnv(key string) (string, bool) {
+	return s.evaluate().lookup(key)
+}
+
+func (s *setupSource) Keys() []string {
+	return s.evaluate().names()
+}
+
+func (s *setupSource) evaluate() *virtualEnvironment {
+	virtual := newVirtualEnvironment(s.base)
+	_, _ = s.setup.applyTo(virtual)
+	return virtual
+}
+
+// an environment that records changes in memory, on top of a (read-only) base Source.
+type virtualEnvironment struct {
+	base    Source
+	changes map[string]*string // a nil value means the variable was unset
+}
+
+func newVirtualEnvironment(base Source) *virtualEnvironment {
+	if base == nil {
+		base = MapSource{}
+	}
+	return &virtualEnvironment{base: base, changes: map[string]*string{}}
+}
+
+func (e *virtualEnvironment) lookup(key string) (string, bool) {
+	if value, ok := e.changes[key]; ok {
+		if value == nil {
+			return "", false
+		}
+		return *value, true
+	}
+	return e.base.LookupEnv(key)
+}
+
+// lists the variables in the environment; variables in the base are only included if the base can
+// list them.
+func (e *virtualEnvironment) names() []string {
+	present := map[string]bool{}
+	if lister, ok := e.base.(keyLister); ok {
+		for _, key := range lister.Keys() {
+			present[key] = true
+		}
+	}
+	for key, value := range e.changes {
+		present[key] = value != nil
+	}
+
+	result := []string{}
+	for key, ok := range present {
+		if ok {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (e *virtualEnvironment) set(key string, value string) error {
+	e.changes[key] = &value
+	return nil
+}
+
+func (e *virtualEnvironment) unset(key string) error {
+	e.changes[key] = nil
+	return nil
+}
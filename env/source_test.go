@@ -0,0 +1,103 @@
+package env
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Source", func() {
+	type expectation struct {
+		value string
+		ok    bool
+	}
+
+	It("OSSource reads the process environment", func() {
+		// Arrange
+		origEnv := New().Set("__test_source_a__", "alpha").Unset("__test_source_b__").Apply()
+		defer origEnv.Apply()
+
+		// Act & Assert
+		value, ok := OSSource.LookupEnv("__test_source_a__")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("alpha"))
+		_, ok = OSSource.LookupEnv("__test_source_b__")
+		Expect(ok).To(BeFalse())
+		keys := OSSource.(keyLister).Keys()
+		Expect(keys).To(ContainElement("__test_source_a__"))
+		Expect(keys).ToNot(ContainElement("__test_source_b__"))
+	})
+
+	It("Setup.Source lists its keys", func() {
+		// Act
+		keys := New().Set("C", "c").Unset("A").Source(MapSource{"A": "a", "B": "b"}).(keyLister).Keys()
+
+		// Assert
+		Expect(keys).To(Equal([]string{"B", "C"}))
+	})
+
+	DescribeTable("MapSource",
+		func(key string, expect expectation) {
+			// Arrange
+			source := MapSource{"FOO": "foo", "EMPTY": ""}
+
+			// Act
+			value, ok := source.LookupEnv(key)
+
+			// Assert
+			Expect(value).To(Equal(expect.value))
+			Expect(ok).To(Equal(expect.ok))
+		},
+		Entry("found", "FOO", expectation{"foo", true}),
+		Entry("found empty", "EMPTY", expectation{"", true}),
+		Entry("not found", "BAR", expectation{"", false}),
+	)
+
+	DescribeTable("Setup.Source",
+		func(setup Setup, base Source, key string, expect expectation) {
+			// Act
+			value, ok := setup.Source(base).LookupEnv(key)
+
+			// Assert
+			Expect(value).To(Equal(expect.value))
+			Expect(ok).To(Equal(expect.ok))
+		},
+		Entry("nil base, not set", New(), nil, "FOO", expectation{"", false}),
+		Entry("nil base, set", New().Set("FOO", "foo"), nil, "FOO", expectation{"foo", true}),
+		Entry("falls back to base", New().Set("FOO", "foo"), MapSource{"BAR": "bar"}, "BAR", expectation{"bar", true}),
+		Entry("overrides base", New().Set("FOO", "foo"), MapSource{"FOO": "bar"}, "FOO", expectation{"foo", true}),
+		Entry("unsets base", New().Unset("FOO"), MapSource{"FOO": "bar"}, "FOO", expectation{"", false}),
+		Entry("last entry wins", New().Set("FOO", "one").Unset("FOO").Set("FOO", "two"), nil, "FOO", expectation{"two", true}),
+	)
+
+	It("Setup.Source does not modify the process environment", func() {
+		// Arrange
+		name := "__test_setup_source__"
+		origEnv := New().Unset(name).Apply()
+		defer origEnv.Apply()
+
+		// Act
+		value, ok := New().Set(name, "virtual").Source(OSSource).LookupEnv(name)
+
+		// Assert
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("virtual"))
+		_, ok = os.LookupEnv(name)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Setup.Source reflects changes to the base", func() {
+		// Arrange
+		base := MapSource{}
+		source := New().Set("FOO", "foo").Source(base)
+
+		// Act
+		base["BAR"] = "bar"
+
+		// Assert
+		value, ok := source.LookupEnv("BAR")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("bar"))
+	})
+})
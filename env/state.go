@@ -0,0 +1,164 @@
+package env
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Adds an entry that will change the working directory of the process (see os.Chdir).  A relative
+// 'dir' is resolved against the working directory at the time the entry is applied.
+//
+// Like all process-state entries, this only affects the process environment; it has no effect on
+// Plan, Source, Environ or ApplyToCmd.
+func (a Setup) Chdir(dir string) Setup {
+	return append(a, &chdirEnv{dir: dir})
+}
+
+// Adds an entry that will set time.Local to the named location (see time.LoadLocation), e.g.
+// "UTC" or "America/New_York".  The TZ variable is not changed; use Set for child processes.
+//
+// time.Local is process-wide: while this is applied it races with any goroutine that formats or
+// parses local times, so it is not safe to use in tests that call t.Parallel unless every such
+// test applies its Setup with GuardLock.  Setups only synchronize their own changes to time.Local.
+func (a Setup) TimeZone(name string) Setup {
+	return append(a, &timeZoneEnv{name: name})
+}
+
+// Adds an entry that will set the file mode creation mask of the process (see umask(2)), e.g.
+// 0o077.  This has no effect on platforms without a umask, such as windows.
+//
+// The umask is process-wide: files created by other goroutines while it is applied are affected.
+func (a Setup) Umask(mask fs.FileMode) Setup {
+	return append(a, &umaskEnv{mask: mask})
+}
+
+// implemented by entries that change process state rather than variables; reverts keep the first
+// inverter for each kind of state, as they do for each key
+type processState interface {
+	state() string
+}
+
+type chdirEnv struct {
+	dir string
+}
+
+func (a *chdirEnv) apply(env environment) (applicator, error) {
+	if _, ok := env.(processEnvironment); !ok {
+		return nil, nil
+	}
+	current, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chdir(a.dir); err != nil {
+		return nil, err
+	}
+	return &chdirEnv{dir: current}, nil
+}
+
+func (a *chdirEnv) validate() error {
+	if a.dir == "" {
+		return fmt.Errorf("chdir: directory is empty")
+	}
+	return nil
+}
+
+func (a *chdirEnv) keys() []string {
+	return nil
+}
+
+func (a *chdirEnv) state() string {
+	return "cwd"
+}
+
+func (a *chdirEnv) String() string {
+	return "chdir " + a.dir
+}
+
+type timeZoneEnv struct {
+	name     string
+	location *time.Location // set for inverters, which restore the original location exactly
+}
+
+func (a *timeZoneEnv) apply(env environment) (applicator, error) {
+	if _, ok := env.(processEnvironment); !ok {
+		return nil, nil
+	}
+	location := a.location
+	if location == nil {
+		var err error
+		if location, err = time.LoadLocation(a.name); err != nil {
+			return nil, fmt.Errorf("time zone %q: %w", a.name, err)
+		}
+	}
+	previous := setLocal(location)
+	return &timeZoneEnv{name: previous.String(), location: previous}, nil
+}
+
+// sets time.Local, returning the previous location; the guard's mutex serializes Setups that
+// change the time zone from different goroutines
+func setLocal(location *time.Location) *time.Location {
+	guardMutex.Lock()
+	defer guardMutex.Unlock()
+	previous := time.Local
+	time.Local = location
+	return previous
+}
+
+func (a *timeZoneEnv) validate() error {
+	if a.location != nil {
+		return nil
+	}
+	if _, err := time.LoadLocation(a.name); err != nil {
+		return fmt.Errorf("time zone %q: %w", a.name, err)
+	}
+	return nil
+}
+
+func (a *timeZoneEnv) keys() []string {
+	return nil
+}
+
+func (a *timeZoneEnv) state() string {
+	return "time zone"
+}
+
+func (a *timeZoneEnv) String() string {
+	return "time zone " + a.name
+}
+
+type umaskEnv struct {
+	mask fs.FileMode
+}
+
+func (a *umaskEnv) apply(env environment) (applicator, error) {
+	if _, ok := env.(processEnvironment); !ok {
+		return nil, nil
+	}
+	previous, ok := setUmask(a.mask)
+	if !ok {
+		return nil, nil
+	}
+	return &umaskEnv{mask: previous}, nil
+}
+
+func (a *umaskEnv) validate() error {
+	if a.mask&^fs.ModePerm != 0 {
+		return fmt.Errorf("umask %#o: only permission bits are allowed", a.mask)
+	}
+	return nil
+}
+
+func (a *umaskEnv) keys() []string {
+	return nil
+}
+
+func (a *umaskEnv) state() string {
+	return "umask"
+}
+
+func (a *umaskEnv) String() string {
+	return fmt.Sprintf("umask %#o", uint32(a.mask))
+}
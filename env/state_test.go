@@ -0,0 +1,207 @@
+package env
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Process state", func() {
+	Context("Chdir", func() {
+		It("should change and restore the working directory", func() {
+			// Arrange
+			original, _ := os.Getwd()
+			dir, _ := filepath.EvalSymlinks(GinkgoT().TempDir())
+
+			// Act
+			revert, err := New().Chdir(dir).ApplyE()
+			inside, _ := os.Getwd()
+			revert.Apply()
+			after, _ := os.Getwd()
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inside).To(Equal(dir))
+			Expect(after).To(Equal(original))
+		})
+
+		It("should restore the original directory after several changes", func() {
+			// Arrange
+			original, _ := os.Getwd()
+			dir := GinkgoT().TempDir()
+			Expect(os.Mkdir(filepath.Join(dir, "sub"), 0o700)).To(Succeed())
+
+			// Act
+			revert, err := New().Chdir(dir).Chdir("sub").ApplyE()
+			inside, _ := os.Getwd()
+			revert.Apply()
+			after, _ := os.Getwd()
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filepath.Base(inside)).To(Equal("sub"))
+			Expect(after).To(Equal(original))
+		})
+
+		It("should roll back other entries if the directory does not exist", func() {
+			// Arrange
+			name := "__test_chdir__"
+			original, _ := os.Getwd()
+			origEnv := New().Unset(name).Apply()
+			defer origEnv.Apply()
+
+			// Act
+			_, err := New().Set(name, "applied").Chdir(filepath.Join(GinkgoT().TempDir(), "missing")).ApplyE()
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(err).To(MatchError(os.ErrNotExist))
+			_, ok := os.LookupEnv(name)
+			Expect(ok).To(BeFalse())
+			after, _ := os.Getwd()
+			Expect(after).To(Equal(original))
+		})
+	})
+
+	Context("TimeZone", func() {
+		It("should change and restore time.Local", func() {
+			// Arrange
+			original := time.Local
+
+			// Act
+			revert, err := New().TimeZone("America/New_York").ApplyE()
+			inside := time.Local.String()
+			revert.Apply()
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inside).To(Equal("America/New_York"))
+			Expect(time.Local).To(BeIdenticalTo(original))
+		})
+
+		It("should change time.Local while holding the guard's mutex", func() {
+			// Arrange
+			original := time.Local
+			guardMutex.Lock()
+			applied := make(chan Setup)
+
+			// Act
+			go func() {
+				applied <- New().TimeZone("America/New_York").Apply()
+			}()
+
+			// Assert
+			time.Sleep(50 * time.Millisecond)
+			guardMutex.Unlock()
+			revert := <-applied
+			Expect(time.Local.String()).To(Equal("America/New_York"))
+			revert.Apply()
+			Expect(time.Local).To(BeIdenticalTo(original))
+		})
+
+		It("should reject unknown time zones", func() {
+			// Act
+			_, err := New().TimeZone("Nowhere/Special").ApplyE()
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(err.Error()).To(ContainSubstring(`time zone "Nowhere/Special"`))
+		})
+	})
+
+	Context("Umask", func() {
+		It("should change and restore the umask", func() {
+			if runtime.GOOS == "windows" {
+				Skip("there is no umask on windows")
+			}
+			// Arrange
+			dir := GinkgoT().TempDir()
+			original, _ := setUmask(0o022)
+			defer setUmask(original)
+
+			// Act
+			revert, err := New().Umask(0o077).ApplyE()
+			Expect(os.WriteFile(filepath.Join(dir, "inside"), nil, 0o666)).To(Succeed())
+			revert.Apply()
+			Expect(os.WriteFile(filepath.Join(dir, "after"), nil, 0o666)).To(Succeed())
+
+			// Assert
+			Expect(err).ToNot(HaveOccurred())
+			inside, _ := os.Stat(filepath.Join(dir, "inside"))
+			after, _ := os.Stat(filepath.Join(dir, "after"))
+			Expect(inside.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+			Expect(after.Mode().Perm()).To(Equal(os.FileMode(0o644)))
+		})
+
+		It("should reject non-permission bits", func() {
+			// Act
+			_, err := New().Umask(os.ModeDir | 0o022).ApplyE()
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(err.Error()).To(ContainSubstring("only permission bits are allowed"))
+		})
+	})
+
+	It("should compose with variables", func() {
+		// Arrange
+		name := "__test_process_state__"
+		original, _ := os.Getwd()
+		originalLocal := time.Local
+		origEnv := New().Unset(name).Apply()
+		defer origEnv.Apply()
+
+		// Act
+		err := New().Set(name, "applied").Chdir(os.TempDir()).TimeZone("UTC").With(func() error {
+			Expect(os.Getenv(name)).To(Equal("applied"))
+			Expect(time.Local.String()).To(Equal("UTC"))
+			return nil
+		})
+
+		// Assert
+		Expect(err).ToNot(HaveOccurred())
+		_, ok := os.LookupEnv(name)
+		Expect(ok).To(BeFalse())
+		after, _ := os.Getwd()
+		Expect(after).To(Equal(original))
+		Expect(time.Local).To(BeIdenticalTo(originalLocal))
+	})
+
+	It("should not affect virtual environments", func() {
+		// Arrange
+		original, _ := os.Getwd()
+		originalLocal := time.Local
+
+		// Act
+		plan := New().Chdir(os.TempDir()).TimeZone("UTC").Umask(0o077).Set("FOO", "foo").planFor(nil)
+
+		// Assert
+		Expect(plan.String()).To(Equal("+ FOO=foo"))
+		after, _ := os.Getwd()
+		Expect(after).To(Equal(original))
+		Expect(time.Local).To(BeIdenticalTo(originalLocal))
+	})
+
+	It("should describe and serialize the entries", func() {
+		// Arrange
+		input := New().Chdir("testdata").TimeZone("UTC").Umask(0o077)
+
+		// Act
+		data, err := json.Marshal(input)
+		decoded := Setup{}
+		Expect(err).ToNot(HaveOccurred())
+		err = json.Unmarshal(data, &decoded)
+
+		// Assert
+		Expect(input.String()).To(Equal("[chdir testdata, time zone UTC, umask 077]"))
+		Expect(string(data)).To(MatchJSON(`[{"op":"chdir","value":"testdata"},{"op":"time_zone","value":"UTC"},
+			{"op":"umask","value":"077"}]`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(input))
+	})
+})
@@ -1,12 +1,13 @@
 package env
 
 import (
+	"encoding"
 	"errors"
 	"fmt"
-	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -18,12 +19,24 @@ var (
 
 var (
 	ErrEnvParseFailure = errors.New("failed to parse env tags")
+	ErrEnvNotSet       = errors.New("required value is not set")
 )
 
 const (
 	tagName     = "envp"
 	propEnv     = "env"
 	propDefault = "default"
+	propSep     = "sep"
+	propKVSep   = "kvsep"
+	propLayout  = "layout"
+	propPrefix  = "prefix"
+
+	flagRequired = "required"
+	flagInline   = "inline"
+	flagSquash   = "squash" // an alias for 'inline'
+
+	defaultSep   = ","
+	defaultKVSep = "="
 
 	errMsgFmt = "%w: %s"
 	errErrFmt = "%w: %w"
@@ -32,8 +45,38 @@ const (
 type tagProperties struct {
 	envSuffix    string // suffix for the environment variable to test
 	defaultValue string // default value as string
+	sep          string // separates the elements of slices and the entries of maps
+	kvSep        string // separates the key and value of map entries
+	layout       string // layout for time.Time values
+	required     bool   // reports an error if there is no value (and no default)
+	prefix       string // prefix for the keys of a nested struct's fields
+	inline       bool   // resolves a nested struct's fields without a prefix
 }
 
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	// time layouts that can be given by name in the 'layout' tag option
+	namedLayouts = map[string]string{
+		"ANSIC":       time.ANSIC,
+		"UnixDate":    time.UnixDate,
+		"RubyDate":    time.RubyDate,
+		"RFC822":      time.RFC822,
+		"RFC822Z":     time.RFC822Z,
+		"RFC850":      time.RFC850,
+		"RFC1123":     time.RFC1123,
+		"RFC1123Z":    time.RFC1123Z,
+		"RFC3339":     time.RFC3339,
+		"RFC3339Nano": time.RFC3339Nano,
+		"Kitchen":     time.Kitchen,
+		"DateTime":    time.DateTime,
+		"DateOnly":    time.DateOnly,
+		"TimeOnly":    time.TimeOnly,
+	}
+)
+
 // Parser for converting 'envp' tags in structs and assigning values to fields, using a 'name' to compose
 // the environment variable name to look up.
 //
@@ -66,7 +109,66 @@ type tagProperties struct {
 // the output would instead be:
 //
 //	{Host: "winston" Port: 1234}
-func ResolveEnvWithName(name string, data interface{}) error {
+//
+// Slice fields ([]T) are read as a list separated by commas, or by the tag's 'sep' option, and
+// map fields (map[string]T) as a list of key=value entries, where the tag's 'kvsep' option can
+// replace the '='.  T can be any of the supported scalar types, and each element is trimmed of
+// surrounding whitespace.  Defaults use the same syntax, quoted with single quotes if they contain
+// commas:
+//
+//	type MyFoo struct {
+//	    Hosts  []string          `envp:"hosts,default='alpha,beta'"`
+//	    Ports  []int             `envp:"ports,sep=;,default=80;443"`
+//	    Labels map[string]string `envp:"labels,kvsep=:,default='team:core,tier:1'"`
+//	}
+//
+// time.Duration fields are parsed with time.ParseDuration (e.g. "30s"), and time.Time fields with
+// the tag's 'layout' option, which is a layout string or the name of one of the time package's
+// layouts (default RFC3339).  Any other type that implements encoding.TextUnmarshaler (e.g.
+// netip.Addr or big.Int) is decoded with its UnmarshalText method, and pointers to any of these
+// types (e.g. *time.Duration) point to a new value.  These fields are left alone when there is no
+// value:
+//
+//	type MyFoo struct {
+//	    Timeout time.Duration `envp:"timeout,default=30s"`
+//	    Since   time.Time     `envp:"since,layout=DateOnly"`
+//	    Address netip.Addr    `envp:"address"`
+//	}
+//
+// Fields tagged 'required' (e.g. `envp:"port,required"`) must have a value, from the environment or
+// a default.  Resolving does not stop at the first problem: the error lists every field that is
+// missing or cannot be parsed, along with the variables that were tried, e.g.
+//
+//	failed to parse env tags:
+//	Port (ENV_FOO_PORT, ENV_PORT): required value is not set
+//	Database.Timeout (ENV_FOO_TIMEOUT, ENV_TIMEOUT): time: invalid duration "soon"
+//
+// The error matches ErrEnvParseFailure, and ErrEnvNotSet if a required field is missing.  Fields
+// that could be parsed are still set.
+//
+// The fields of nested structs (and pointers to structs) are resolved in turn, using the same keys
+// as the fields of the outer struct unless the field has a 'prefix', which is added to the keys of
+// its fields.  Prefixes compose, and 'inline' (or 'squash') resolves a nested struct without one:
+//
+//	type MyDB struct {
+//	    Host string `envp:"host"`
+//	}
+//	type MyFoo struct {
+//	    Primary MyDB `envp:"prefix=primary"`   // ENV_FOO_PRIMARY_HOST / ENV_PRIMARY_HOST
+//	    Replica MyDB `envp:"prefix=replica"`   // ENV_FOO_REPLICA_HOST / ENV_REPLICA_HOST
+//	    Default MyDB `envp:"inline"`           // ENV_FOO_HOST / ENV_HOST
+//	}
+//
+// Both are reported as errors on fields that are not nested structs, as are 'prefix' combined with
+// 'inline' and 'required' on a nested struct.  Since 'required', 'inline' and 'squash' are flags,
+// use 'env=' for a variable with one of those names (e.g. "env=required").
+//
+// Decoders for other types (or to replace the built-in conversions) can be added with
+// RegisterDecoder, or for a single call with the WithDecoder option.
+//
+// Values are read from the process environment unless you provide a different Source using the
+// WithSource option.
+func ResolveEnvWithName(name string, data interface{}, opts ...ResolveOption) error {
 	if data == nil {
 		// nothing to do
 		return nil
@@ -78,20 +180,42 @@ func ResolveEnvWithName(name string, data interface{}) error {
 		return nil
 	}
 
-	parser := envpTagParser{name: name}
-	return parser.resolve(reflect.ValueOf(data).Elem())
+	parser := envpTagParser{name: name, source: OSSource}
+	for _, opt := range opts {
+		opt(&parser)
+	}
+	return joinFieldErrors(parser.resolve(reflect.ValueOf(data).Elem(), "", ""))
 }
 
 // allows parsing 'envp' tags without requiring a 'name' (so it would only look up 'base' environment values)
-func ResolveEnv(data interface{}) error {
-	return ResolveEnvWithName("", data)
+//
+// Note that this only looks up the base variables (e.g. "ENV_HOST"); before fields could be required,
+// it also looked up a variable for the empty name (e.g. "ENV__HOST"), which is no longer read.
+func ResolveEnv(data interface{}, opts ...ResolveOption) error {
+	return ResolveEnvWithName("", data, opts...)
+}
+
+// Customizes the behaviour of ResolveEnv and ResolveEnvWithName.
+type ResolveOption func(p *envpTagParser)
+
+// Resolves values from the given Source instead of the process environment.
+func WithSource(source Source) ResolveOption {
+	return func(p *envpTagParser) {
+		p.source = source
+	}
 }
 
 type envpTagParser struct {
-	name string
+	name     string
+	source   Source
+	decoders map[reflect.Type]decoder // given with WithDecoder
 }
 
-func (p *envpTagParser) resolve(value reflect.Value) error {
+// resolves the fields of the struct, returning an error for each field that is missing or cannot be
+// parsed; 'path' is the path to the struct from the value being resolved (e.g. "Database.") and
+// 'keyPrefix' is added to the keys of its fields (e.g. "PRIMARY_")
+func (p *envpTagParser) resolve(value reflect.Value, path string, keyPrefix string) []error {
+	var errs []error
 	for index := 0; index < value.Type().NumField(); index++ {
 		field := value.Field(index)
 		if !field.IsZero() || !field.CanSet() {
@@ -100,103 +224,421 @@ func (p *envpTagParser) resolve(value reflect.Value) error {
 			continue
 		}
 		fieldType := value.Type().Field(index)
-		newValue := p.resolveFieldValue(fieldType)
-		if err := p.setFieldValue(value.Field(index), newValue); err != nil {
-			return err
+		properties := p.getTagProperties(fieldType.Tag.Get(tagName))
+		if p.isNested(field.Type()) {
+			if err := nestedTagError(properties); err != nil {
+				errs = append(errs, &FieldError{Path: path + fieldType.Name, Type: field.Type(), Err: err})
+				continue
+			}
+			nestedPrefix := keyPrefix
+			if properties.prefix != "" {
+				nestedPrefix += strings.ToUpper(properties.prefix) + "_"
+			}
+			errs = append(errs, p.resolveNested(field, path+fieldType.Name+".", nestedPrefix)...)
+			continue
+		}
+
+		tried := p.envNames(keyPrefix + properties.envSuffix)
+		if properties.prefix != "" || properties.inline {
+			// most likely a mistake, e.g. a struct field that has a decoder
+			errs = append(errs, &FieldError{
+				Path:  path + fieldType.Name,
+				Tried: tried,
+				Type:  field.Type(),
+				Err:   fmt.Errorf("'%s' and '%s' only apply to nested structs", propPrefix, flagInline),
+			})
+			continue
+		}
+		newValue, envName, fromDefault := p.getEnvWithDefault(tried, properties.defaultValue)
+		err := p.setFieldValue(field, newValue, properties)
+		if err == nil && newValue == "" && properties.required {
+			err = ErrEnvNotSet
+		}
+		if err != nil {
+			secretName := envName
+			if secretName == "" {
+				// a default is as secret as the variables it stands in for
+				secretName = tried[len(tried)-1]
+			}
+			reportedValue := redact(secretName, newValue, false)
+			if reportedValue == RedactedValue {
+				// parse errors typically quote the value, or one of its elements
+				err = &maskedError{err: err}
+			}
+			errs = append(errs, &FieldError{
+				Path:        path + fieldType.Name,
+				EnvVar:      envName,
+				FromDefault: fromDefault,
+				Tried:       tried,
+				Value:       reportedValue,
+				Type:        field.Type(),
+				Err:         err,
+			})
 		}
 	}
+	return errs
+}
+
+// reports whether fields of the type are structs whose fields are resolved in turn, rather than
+// values that are read from a variable
+func (p *envpTagParser) isNested(t reflect.Type) bool {
+	if _, ok := p.decoderFor(t); ok || isTextType(t) {
+		return false
+	}
+	if t.Kind() == reflect.Pointer {
+		if _, ok := p.decoderFor(t.Elem()); ok {
+			return false
+		}
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// reports tag options that a nested struct's field cannot honour, which are most likely mistakes
+func nestedTagError(properties tagProperties) error {
+	switch {
+	case properties.prefix != "" && properties.inline:
+		return fmt.Errorf("'%s' and '%s' cannot be combined", propPrefix, flagInline)
+	case properties.required:
+		return fmt.Errorf("'%s' does not apply to nested structs; mark their fields instead", flagRequired)
+	}
 	return nil
 }
 
-func (p *envpTagParser) resolveFieldValue(field reflect.StructField) string {
-	tag := field.Tag.Get(tagName)
-	properties := p.getTagProperties(tag)
-	value := p.getEnvWithDefault(properties.envSuffix, properties.defaultValue)
-	return value
+func (p *envpTagParser) resolveNested(field reflect.Value, path string, keyPrefix string) []error {
+	if field.Kind() == reflect.Struct {
+		return p.resolve(field, path, keyPrefix)
+	}
+	if field.IsNil() {
+		field.Set(reflect.New(field.Type().Elem()))
+	}
+	return p.resolve(field.Elem(), path, keyPrefix)
 }
 
-func (p *envpTagParser) setFieldValue(field reflect.Value, value string) error {
-	var err error
+// sets the field from its value; an empty value (i.e. no value or default) leaves the field alone
+func (p *envpTagParser) setFieldValue(field reflect.Value, value string, properties tagProperties) error {
+	if value == "" {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Slice:
+		if _, ok := p.decoderFor(field.Type()); !ok && !isTextType(field.Type()) {
+			return p.setSliceValue(field, value, properties)
+		}
+	case reflect.Map:
+		if _, ok := p.decoderFor(field.Type()); !ok && !isTextType(field.Type()) {
+			return p.setMapValue(field, value, properties)
+		}
+	}
+	return p.setScalarValue(field, value, properties)
+}
 
+// sets a single value, e.g. a field or the element of a slice
+func (p *envpTagParser) setScalarValue(field reflect.Value, value string, properties tagProperties) error {
+	if decoded, err := p.setDecodedValue(field, value); decoded {
+		return err
+	}
+	if isTextType(field.Type()) {
+		return p.setTextValue(field, value, properties)
+	}
+
+	var err error
 	switch field.Kind() {
 	case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
 		var intValue int64
 		if intValue, err = strconv.ParseInt(value, 0, 64); err != nil {
-			return fmt.Errorf(errErrFmt, ErrEnvParseFailure, err)
+			return err
 		}
 		field.SetInt(intValue)
 	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		var uintValue uint64
 		if uintValue, err = strconv.ParseUint(value, 0, 64); err != nil {
-			return fmt.Errorf(errErrFmt, ErrEnvParseFailure, err)
+			return err
 		}
 		field.SetUint(uintValue)
 	case reflect.Float32, reflect.Float64:
 		var f64Value float64
 		if f64Value, err = strconv.ParseFloat(value, 64); err != nil {
-			return fmt.Errorf(errErrFmt, ErrEnvParseFailure, err)
+			return err
 		}
 		field.SetFloat(f64Value)
 	case reflect.Bool:
 		var boolValue bool
 		if boolValue, err = strconv.ParseBool(value); err != nil {
-			return fmt.Errorf(errErrFmt, ErrEnvParseFailure, err)
+			return err
 		}
 		field.SetBool(boolValue)
 	case reflect.String:
 		field.SetString(value)
-	case reflect.Struct:
-		// recursive
-		p.resolve(field)
-	case reflect.Pointer, reflect.Interface:
-		if field.IsNil() {
-			field.Set(reflect.New(field.Type().Elem()))
-		}
-		// recursive
-		p.resolve(field.Elem())
 	default:
-		return fmt.Errorf("%w: unsupported field type '%s'", ErrEnvParseFailure, field.Kind().String())
+		return fmt.Errorf("unsupported field type '%s'", field.Kind().String())
 	}
 
 	return nil
 }
 
+// reports whether values of the type (or, for a pointer, the type it points to) are decoded from
+// text rather than according to their kind
+func isTextType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		return isTextType(t.Elem())
+	}
+	return t == durationType || t == timeType || t.Implements(textUnmarshalerType) ||
+		reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// sets a time.Duration, time.Time or encoding.TextUnmarshaler; an empty value leaves it alone
+func (p *envpTagParser) setTextValue(field reflect.Value, value string, properties tagProperties) error {
+	if value == "" {
+		return nil
+	}
+
+	switch {
+	case field.Type() == durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+	case field.Type() == timeType:
+		layout := time.RFC3339
+		if properties.layout != "" {
+			layout = properties.layout
+		}
+		if named, ok := namedLayouts[layout]; ok {
+			layout = named
+		}
+		parsed, err := time.Parse(layout, value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(parsed))
+	case field.Kind() == reflect.Pointer:
+		// e.g. *big.Int or *time.Time; only replace the field once the value has been decoded
+		decoded := reflect.New(field.Type().Elem())
+		if err := p.setTextValue(decoded.Elem(), value, properties); err != nil {
+			return err
+		}
+		field.Set(decoded)
+	default:
+		decoded := reflect.New(field.Type())
+		if err := decoded.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return err
+		}
+		field.Set(decoded.Elem())
+	}
+	return nil
+}
+
+// sets the slice from a list of elements, e.g. "a,b,c"; an empty value leaves the slice alone
+func (p *envpTagParser) setSliceValue(field reflect.Value, value string, properties tagProperties) error {
+	if value == "" {
+		return nil
+	}
+	elements := strings.Split(value, properties.sep)
+	slice := reflect.MakeSlice(field.Type(), len(elements), len(elements))
+	for index, element := range elements {
+		if err := p.setScalarValue(slice.Index(index), strings.TrimSpace(element), properties); err != nil {
+			return elementError(fmt.Sprintf("element %d", index), err)
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// sets the map from a list of entries, e.g. "a=1,b=2"; an empty value leaves the map alone
+func (p *envpTagParser) setMapValue(field reflect.Value, value string, properties tagProperties) error {
+	if value == "" {
+		return nil
+	}
+	if field.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("unsupported map key type '%s'", field.Type().Key().Kind().String())
+	}
+	result := reflect.MakeMap(field.Type())
+	for index, entry := range strings.Split(value, properties.sep) {
+		key, element, found := strings.Cut(entry, properties.kvSep)
+		if !found {
+			return fmt.Errorf("entry %d: missing '%s'", index, properties.kvSep)
+		}
+		mapValue := reflect.New(field.Type().Elem()).Elem()
+		if err := p.setScalarValue(mapValue, strings.TrimSpace(element), properties); err != nil {
+			return elementError(fmt.Sprintf("entry %d", index), err)
+		}
+		result.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)).Convert(field.Type().Key()), mapValue)
+	}
+	field.Set(result)
+	return nil
+}
+
+// reports which element could not be parsed; numeric errors are reduced to their cause, which
+// leaves out the element they quote
+func elementError(element string, err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		err = numErr.Err
+	}
+	return fmt.Errorf("%s: %w", element, err)
+}
+
+// Parses the tag's comma-separated properties.  Flags (e.g. "required") may appear anywhere, and a
+// part without "=" is the env name.  A value that contains commas must be quoted with single
+// quotes, e.g. "default='a,b'" or "layout='Mon, 02 Jan 2006'".
 func (p *envpTagParser) getTagProperties(tag string) tagProperties {
 	properties := tagProperties{}
-	params := strings.Split(tag, ",")
-	for _, p := range params {
-		trimmedParam := strings.TrimSpace(p)
-		keyValue := strings.SplitN(trimmedParam, "=", 2)
-		switch keyValue[0] {
-		case trimmedParam: // specified without "="; assumes the 'env' prefix
+	for _, param := range splitTag(tag) {
+		trimmedParam := strings.TrimSpace(param)
+		key, value, hasValue := strings.Cut(trimmedParam, "=")
+		switch target := properties.named(key); {
+		case properties.flag(trimmedParam) != nil:
+			*properties.flag(trimmedParam) = true
+		case hasValue && target != nil:
+			*target = unquoteTagValue(value)
+		case !hasValue && trimmedParam != "": // specified without "="; assumes the 'env' prefix
 			properties.envSuffix = trimmedParam
-		case propEnv:
-			properties.envSuffix = keyValue[1]
-		case propDefault:
-			properties.defaultValue = keyValue[1]
 		}
 	}
+
+	if properties.sep == "" {
+		properties.sep = defaultSep
+	}
+	if properties.kvSep == "" {
+		properties.kvSep = defaultKVSep
+	}
 	return properties
 }
 
-func (p *envpTagParser) getEnvWithDefault(key string, defaultValue string) string {
+// splits the tag on the commas that are not inside a quoted value
+func splitTag(tag string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for index := 0; index < len(tag); index++ {
+		switch tag[index] {
+		case '\'':
+			// only a quote at the start of a value opens one
+			if quoted || (index > 0 && tag[index-1] == '=') {
+				quoted = !quoted
+			}
+		case ',':
+			if !quoted {
+				parts = append(parts, tag[start:index])
+				start = index + 1
+			}
+		}
+	}
+	return append(parts, tag[start:])
+}
+
+// removes the single quotes around a value, keeping the whitespace inside them
+func unquoteTagValue(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// returns the property with the given name, or nil if there is no such property
+func (t *tagProperties) named(name string) *string {
+	switch name {
+	case propEnv:
+		return &t.envSuffix
+	case propDefault:
+		return &t.defaultValue
+	case propSep:
+		return &t.sep
+	case propKVSep:
+		return &t.kvSep
+	case propLayout:
+		return &t.layout
+	case propPrefix:
+		return &t.prefix
+	}
+	return nil
+}
+
+// returns the flag with the given name, or nil if there is no such flag
+func (t *tagProperties) flag(name string) *bool {
+	switch name {
+	case flagRequired:
+		return &t.required
+	case flagInline, flagSquash:
+		return &t.inline
+	}
+	return nil
+}
+
+// returns the names of the variables to look up for the key, in order of preference
+func (p *envpTagParser) envNames(key string) []string {
 	// e.g. ("bags", "bag_size") => "ENV_BAGS_BAG_SIZE" / "ENV_BAG_SIZE"
 	// e.g. ("bytes", "foo_bar") => "ENV_BYTES_FOO_BAR" / "ENV_FOO_BAR"
-	envNames := []string{
-		// order matters here
-		fmt.Sprintf("%s%s_%s", EnvTagPrefix, strings.ToUpper(p.name), strings.ToUpper(key)),
-		fmt.Sprintf("%s%s", EnvTagPrefix, strings.ToUpper(key)),
+	baseName := fmt.Sprintf("%s%s", EnvTagPrefix, strings.ToUpper(key))
+	if p.name == "" {
+		return []string{baseName}
 	}
+	return []string{fmt.Sprintf("%s%s_%s", EnvTagPrefix, strings.ToUpper(p.name), strings.ToUpper(key)), baseName}
+}
 
+// returns the value of the first of the variables that is set and its name, or the default value
+// and an empty name, reporting whether the value is the default
+func (p *envpTagParser) getEnvWithDefault(envNames []string, defaultValue string) (string, string, bool) {
 	result := ""
+	resultName := ""
 	for _, envName := range envNames {
-		if value, found := os.LookupEnv(envName); found {
+		if value, found := p.source.LookupEnv(envName); found {
 			result = value
+			resultName = envName
 			break
 		}
 	}
 	if result == "" {
-		result = defaultValue
+		return defaultValue, "", defaultValue != ""
+	}
+	return result, resultName, false
+}
+
+// Describes a field that ResolveEnv or ResolveEnvWithName could not set, because it is required and
+// has no value or because its value cannot be parsed.  The error returned by ResolveEnv includes a
+// FieldError for each such field, which can be retrieved with errors.As:
+//
+//	var fieldErr *env.FieldError
+//	if errors.As(err, &fieldErr) {
+//	    log.Printf("bad value %q in %s for %s", fieldErr.Value, fieldErr.EnvVar, fieldErr.Path)
+//	}
+//
+// A FieldError matches ErrEnvParseFailure, as well as its cause (e.g. ErrEnvNotSet).
+type FieldError struct {
+	Path        string       // the path to the struct field, e.g. "DB.Pool.Max"
+	EnvVar      string       // the variable that supplied the value, or "" if none did (see Tried)
+	FromDefault bool         // whether the value is the tag's default, because none of the variables were set
+	Tried       []string     // the variables that were looked up, in order of preference
+	Value       string       // the value, or RedactedValue if the variable holds a secret
+	Type        reflect.Type // the type of the field
+	Err         error        // the cause, e.g. ErrEnvNotSet or a *strconv.NumError (with its message hidden for secrets)
+}
+
+func (e *FieldError) Error() string {
+	if len(e.Tried) == 0 {
+		// e.g. a nested struct, which is not read from a variable
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s (%s): %v", e.Path, strings.Join(e.Tried, ", "), e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FieldError) Is(target error) bool {
+	return target == ErrEnvParseFailure
+}
+
+// combines the errors for fields into one error that matches ErrEnvParseFailure
+func joinFieldErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf(errErrFmt, ErrEnvParseFailure, errs[0])
 	}
-	return result
+	return fmt.Errorf("%w:\n%w", ErrEnvParseFailure, errors.Join(errs...))
 }
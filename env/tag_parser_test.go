@@ -1,6 +1,13 @@
 package env
 
 import (
+	"errors"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -97,6 +104,24 @@ var _ = Describe("Env Tag Parser", func() {
 			Expect(err).To(MatchError(ErrEnvParseFailure))
 		})
 
+		It("will redact secret values in parse errors", func() {
+			// Arrange
+			type TestStruct struct {
+				Value int `envp:"env=db_password"`
+			}
+			origEnv := New().Set("ENV_TEST_DB_PASSWORD", "hunter2").Apply()
+			defer origEnv.Apply()
+
+			// Act
+			var s TestStruct
+			err := ResolveEnvWithName("test", &s)
+
+			// Assert
+			Expect(err).To(MatchError(ErrEnvParseFailure))
+			Expect(err.Error()).ToNot(ContainSubstring("hunter2"))
+			Expect(err.Error()).To(ContainSubstring(RedactedValue))
+		})
+
 		It("will ignore unsettable fields", func() {
 			// Arrange
 			type TestStruct struct {
@@ -152,6 +177,45 @@ var _ = Describe("Env Tag Parser", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("will use the source provided with WithSource", func() {
+			// Arrange
+			type TestStruct struct {
+				Value int    `envp:"env=value,default=10"`
+				Name  string `envp:"env=name,default=none"`
+			}
+			origEnv := testEnv.Apply()
+			defer origEnv.Apply()
+			source := MapSource{"ENV_TEST_VALUE": "42"}
+
+			// Act
+			s := TestStruct{}
+			err := ResolveEnvWithName("test", &s, WithSource(source))
+
+			// Assert
+			Expect(s.Value).To(Equal(42))
+			Expect(s.Name).To(Equal("none"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("will resolve from a setup without applying it", func() {
+			// Arrange
+			type TestStruct struct {
+				Value int `envp:"env=value,default=10"`
+			}
+			origEnv := noEnv.Apply()
+			defer origEnv.Apply()
+
+			// Act
+			s := TestStruct{}
+			err := ResolveEnv(&s, WithSource(baseEnv.Source(OSSource)))
+
+			// Assert
+			Expect(s.Value).To(Equal(1))
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := OSSource.LookupEnv("ENV_VALUE")
+			Expect(ok).To(BeFalse())
+		})
+
 		Context("signed integers", func() {
 			DescribeTable("will convert signed int",
 				func(testEnv Setup, input int, expectedValue int, expectedErr error) {
@@ -513,6 +577,228 @@ var _ = Describe("Env Tag Parser", func() {
 			)
 		})
 
+		Context("slices", func() {
+			type TestStruct struct {
+				Hosts  []string  `envp:"hosts,default='alpha, beta'"`
+				Ports  []int     `envp:"ports,sep=;,default=80;443"`
+				Ratios []float64 `envp:"ratios,sep=','"`
+				Flags  []bool    `envp:"flags"`
+				Sizes  []uint16  `envp:"sizes"`
+			}
+
+			DescribeTable("will convert slices",
+				func(source MapSource, expected TestStruct) {
+					// Act
+					var s TestStruct
+					err := ResolveEnvWithName("test", &s, WithSource(source))
+
+					// Assert
+					Expect(err).ToNot(HaveOccurred())
+					Expect(s).To(Equal(expected))
+				},
+				Entry("no env returns defaults", MapSource{},
+					TestStruct{Hosts: []string{"alpha", "beta"}, Ports: []int{80, 443}}),
+				Entry("env values", MapSource{"ENV_HOSTS": "a,b , c", "ENV_TEST_PORTS": "1;2", "ENV_RATIOS": "0.5",
+					"ENV_FLAGS": "true,false", "ENV_SIZES": "0x10,2"},
+					TestStruct{Hosts: []string{"a", "b", "c"}, Ports: []int{1, 2}, Ratios: []float64{0.5},
+						Flags: []bool{true, false}, Sizes: []uint16{16, 2}}),
+				Entry("empty elements are kept", MapSource{"ENV_HOSTS": "a,,b"}, TestStruct{Hosts: []string{"a", "", "b"},
+					Ports: []int{80, 443}}),
+			)
+
+			It("will not replace a non-empty slice", func() {
+				// Act
+				s := TestStruct{Hosts: []string{"preset"}}
+				err := ResolveEnv(&s, WithSource(MapSource{"ENV_HOSTS": "a,b"}))
+
+				// Assert
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.Hosts).To(Equal([]string{"preset"}))
+			})
+
+			It("will report the element that cannot be parsed", func() {
+				// Arrange
+				type SecretStruct struct {
+					Pins []int `envp:"pin_password"`
+				}
+
+				// Act
+				var s TestStruct
+				err := ResolveEnv(&s, WithSource(MapSource{"ENV_PORTS": "80;http"}))
+				var secret SecretStruct
+				secretErr := ResolveEnv(&secret, WithSource(MapSource{"ENV_PIN_PASSWORD": "1234,abcd"}))
+
+				// Assert
+				Expect(err).To(MatchError(ErrEnvParseFailure))
+				Expect(err.Error()).To(Equal("failed to parse env tags: Ports (ENV_PORTS): element 1: invalid syntax"))
+				Expect(secretErr).To(MatchError(ErrEnvParseFailure))
+				Expect(secretErr.Error()).ToNot(ContainSubstring("abcd"))
+			})
+
+			It("will report unsupported element types", func() {
+				// Arrange
+				type BadStruct struct {
+					Values []complex64 `envp:"values"`
+				}
+
+				// Act
+				var s BadStruct
+				err := ResolveEnv(&s, WithSource(MapSource{"ENV_VALUES": "1"}))
+
+				// Assert
+				Expect(err).To(MatchError(ErrEnvParseFailure))
+				Expect(err.Error()).To(ContainSubstring("element 0: unsupported field type 'complex64'"))
+			})
+		})
+
+		Context("maps", func() {
+			type Label string
+			type TestStruct struct {
+				Labels  map[string]string `envp:"labels,default='team=core, tier = 1'"`
+				Weights map[string]int    `envp:"weights,sep=;,kvsep=:,default=a:1;b:2"`
+				Named   map[Label]bool    `envp:"named"`
+			}
+
+			DescribeTable("will convert maps",
+				func(source MapSource, expected TestStruct) {
+					// Act
+					var s TestStruct
+					err := ResolveEnvWithName("test", &s, WithSource(source))
+
+					// Assert
+					Expect(err).ToNot(HaveOccurred())
+					Expect(s).To(Equal(expected))
+				},
+				Entry("no env returns defaults", MapSource{},
+					TestStruct{Labels: map[string]string{"team": "core", "tier": "1"}, Weights: map[string]int{"a": 1, "b": 2}}),
+				Entry("env values", MapSource{"ENV_LABELS": "x=1=2", "ENV_TEST_WEIGHTS": "c:3", "ENV_NAMED": "on=true,off=false"},
+					TestStruct{Labels: map[string]string{"x": "1=2"}, Weights: map[string]int{"c": 3},
+						Named: map[Label]bool{"on": true, "off": false}}),
+				Entry("repeated keys keep the last value", MapSource{"ENV_LABELS": "a=1,a=2"},
+					TestStruct{Labels: map[string]string{"a": "2"}, Weights: map[string]int{"a": 1, "b": 2}}),
+			)
+
+			DescribeTable("will report invalid maps",
+				func(source MapSource, expectedMsg string) {
+					// Act
+					var s TestStruct
+					err := ResolveEnv(&s, WithSource(source))
+
+					// Assert
+					Expect(err).To(MatchError(ErrEnvParseFailure))
+					Expect(err.Error()).To(Equal(expectedMsg))
+				},
+				Entry("missing separator", MapSource{"ENV_LABELS": "a=1,b"}, "failed to parse env tags: Labels (ENV_LABELS): entry 1: missing '='"),
+				Entry("invalid value", MapSource{"ENV_WEIGHTS": "a:x"}, "failed to parse env tags: Weights (ENV_WEIGHTS): entry 0: invalid syntax"),
+			)
+
+			It("will report unsupported key types", func() {
+				// Arrange
+				type BadStruct struct {
+					Values map[int]string `envp:"values"`
+				}
+
+				// Act
+				var s BadStruct
+				err := ResolveEnv(&s, WithSource(MapSource{"ENV_VALUES": "1=a"}))
+
+				// Assert
+				Expect(err).To(MatchError(ErrEnvParseFailure))
+				Expect(err.Error()).To(ContainSubstring("unsupported map key type 'int'"))
+			})
+		})
+
+		DescribeTable("tag properties",
+			func(tag string, expected tagProperties) {
+				Expect((&envpTagParser{}).getTagProperties(tag)).To(Equal(expected))
+			},
+			Entry("empty", "", tagProperties{sep: ",", kvSep: "="}),
+			Entry("bare name", "value", tagProperties{envSuffix: "value", sep: ",", kvSep: "="}),
+			Entry("named properties", "env=value, default=10", tagProperties{envSuffix: "value", defaultValue: "10", sep: ",", kvSep: "="}),
+			Entry("quoted default with commas", "value,default='a, b,c'", tagProperties{envSuffix: "value", defaultValue: "a, b,c", sep: ",", kvSep: "="}),
+			Entry("quoted default with pairs", "value,default='a=1,b=2'", tagProperties{envSuffix: "value", defaultValue: "a=1,b=2", sep: ",", kvSep: "="}),
+			Entry("name after a default", "default=5,port", tagProperties{envSuffix: "port", defaultValue: "5", sep: ",", kvSep: "="}),
+			Entry("empty parts are ignored", "value,,sep=,", tagProperties{envSuffix: "value", sep: ",", kvSep: "="}),
+			Entry("quotes inside a value", "value,default=it's", tagProperties{envSuffix: "value", defaultValue: "it's", sep: ",", kvSep: "="}),
+			Entry("separators", "value,sep=;,kvsep=:", tagProperties{envSuffix: "value", sep: ";", kvSep: ":"}),
+			Entry("comma separator", "value,sep=',',default=a", tagProperties{envSuffix: "value", defaultValue: "a", sep: ",", kvSep: "="}),
+			Entry("unknown properties are ignored", "foo=bar,value", tagProperties{envSuffix: "value", sep: ",", kvSep: "="}),
+			Entry("required", "value,required", tagProperties{envSuffix: "value", required: true, sep: ",", kvSep: "="}),
+			Entry("required after a default", "value,default='a,b',required", tagProperties{envSuffix: "value", defaultValue: "a,b",
+				required: true, sep: ",", kvSep: "="}),
+			Entry("prefix", "prefix=primary", tagProperties{prefix: "primary", sep: ",", kvSep: "="}),
+			Entry("inline", "inline", tagProperties{inline: true, sep: ",", kvSep: "="}),
+			Entry("squash", "squash", tagProperties{inline: true, sep: ",", kvSep: "="}),
+			Entry("reserved name", "env=required", tagProperties{envSuffix: "required", sep: ",", kvSep: "="}),
+		)
+
+		Context("text values", func() {
+			type TestStruct struct {
+				Timeout  time.Duration   `envp:"timeout,default=30s"`
+				Since    time.Time       `envp:"since"`
+				Day      time.Time       `envp:"day,layout=DateOnly"`
+				Stamp    time.Time       `envp:"stamp,layout='Mon, 02 Jan 2006'"`
+				Address  netip.Addr      `envp:"address"`
+				Total    *big.Int        `envp:"total"`
+				Backoffs []time.Duration `envp:"backoffs"`
+				Wait     *time.Duration  `envp:"wait"`
+				Expires  *time.Time      `envp:"expires,layout=DateOnly"`
+			}
+
+			It("will leave text values alone when there is no value", func() {
+				// Act
+				var s TestStruct
+				err := ResolveEnv(&s, WithSource(MapSource{}))
+
+				// Assert
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s).To(Equal(TestStruct{Timeout: 30 * time.Second}))
+			})
+
+			It("will decode durations, times and text unmarshalers", func() {
+				// Arrange
+				source := MapSource{"ENV_TIMEOUT": "1m30s", "ENV_SINCE": "2024-05-06T07:08:09Z", "ENV_DAY": "2024-05-06",
+					"ENV_STAMP": "Mon, 06 May 2024", "ENV_ADDRESS": "10.0.0.1", "ENV_TOTAL": "123456789012345678901234567890",
+					"ENV_BACKOFFS": "1s, 2s,4s", "ENV_WAIT": "5s", "ENV_EXPIRES": "2024-05-06"}
+
+				// Act
+				var s TestStruct
+				err := ResolveEnv(&s, WithSource(source))
+
+				// Assert
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.Timeout).To(Equal(90 * time.Second))
+				Expect(s.Since).To(Equal(time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)))
+				Expect(s.Day).To(Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)))
+				Expect(s.Stamp).To(Equal(s.Day))
+				Expect(s.Address).To(Equal(netip.MustParseAddr("10.0.0.1")))
+				Expect(s.Total.String()).To(Equal("123456789012345678901234567890"))
+				Expect(s.Backoffs).To(Equal([]time.Duration{time.Second, 2 * time.Second, 4 * time.Second}))
+				Expect(s.Wait).To(HaveValue(Equal(5 * time.Second)))
+				Expect(s.Expires).To(HaveValue(Equal(s.Day)))
+			})
+
+			DescribeTable("will report values that cannot be decoded",
+				func(source MapSource, expectedMsg string) {
+					// Act
+					var s TestStruct
+					err := ResolveEnv(&s, WithSource(source))
+
+					// Assert
+					Expect(err).To(MatchError(ErrEnvParseFailure))
+					Expect(err.Error()).To(ContainSubstring(expectedMsg))
+					Expect(s.Total).To(BeNil())
+				},
+				Entry("duration", MapSource{"ENV_TIMEOUT": "soon"}, "invalid duration"),
+				Entry("time", MapSource{"ENV_DAY": "06/05/2024"}, "cannot parse"),
+				Entry("text unmarshaler", MapSource{"ENV_ADDRESS": "10.0.0"}, "ParseAddr"),
+				Entry("pointer to text unmarshaler", MapSource{"ENV_TOTAL": "lots"}, "math/big"),
+				Entry("slice element", MapSource{"ENV_BACKOFFS": "1s,never"}, `element 1: time: invalid duration "never"`),
+				Entry("pointer to duration", MapSource{"ENV_WAIT": "later"}, "invalid duration"),
+				Entry("pointer to time", MapSource{"ENV_EXPIRES": "2024-05-06T07:08:09Z"}, "extra text"),
+			)
+		})
+
 		Context("nested structs", func() {
 			var (
 				noEnv   = New().Unset("ENV_VALUE").Unset("ENV_TEST_VALUE").Unset("ENV_FAB").Unset("ENV_TEST_FAB")
@@ -571,6 +857,254 @@ var _ = Describe("Env Tag Parser", func() {
 				Expect(s.Inner.Fab).To(Equal("ulous"))
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			Context("prefixes", func() {
+				type DBStruct struct {
+					Host string `envp:"host,default=localhost"`
+					Port int    `envp:"port,required"`
+				}
+				type ClusterStruct struct {
+					Primary DBStruct  `envp:"prefix=primary"`
+					Replica *DBStruct `envp:"prefix=replica"`
+				}
+				type TestStruct struct {
+					Default DBStruct      `envp:"inline"`
+					Squash  DBStruct      `envp:"squash"`
+					Cluster ClusterStruct `envp:"prefix=cluster"`
+				}
+
+				It("will add prefixes to the keys of nested fields", func() {
+					// Arrange
+					source := MapSource{"ENV_HOST": "base", "ENV_PORT": "1", "ENV_TEST_CLUSTER_PRIMARY_HOST": "primary",
+						"ENV_CLUSTER_PRIMARY_PORT": "2", "ENV_CLUSTER_REPLICA_HOST": "replica", "ENV_TEST_CLUSTER_REPLICA_PORT": "3"}
+
+					// Act
+					var s TestStruct
+					err := ResolveEnvWithName("test", &s, WithSource(source))
+
+					// Assert
+					Expect(err).ToNot(HaveOccurred())
+					Expect(s.Default).To(Equal(DBStruct{Host: "base", Port: 1}))
+					Expect(s.Squash).To(Equal(s.Default))
+					Expect(s.Cluster.Primary).To(Equal(DBStruct{Host: "primary", Port: 2}))
+					Expect(s.Cluster.Replica).To(Equal(&DBStruct{Host: "replica", Port: 3}))
+				})
+
+				It("will report the prefixed variables", func() {
+					// Act
+					var s ClusterStruct
+					err := ResolveEnvWithName("test", &s, WithSource(MapSource{"ENV_PRIMARY_PORT": "2"}))
+
+					// Assert
+					Expect(err).To(MatchError(ErrEnvNotSet))
+					Expect(err.Error()).To(Equal("failed to parse env tags: " +
+						"Replica.Port (ENV_TEST_REPLICA_PORT, ENV_REPLICA_PORT): required value is not set"))
+					Expect(s.Primary.Host).To(Equal("localhost"))
+				})
+
+				It("will report options that nested structs cannot honour", func() {
+					// Arrange
+					type BadStruct struct {
+						Primary  DBStruct  `envp:"prefix=a,inline"`
+						Replica  *DBStruct `envp:"required"`
+						Fallback DBStruct  `envp:"prefix=b"`
+					}
+
+					// Act
+					var s BadStruct
+					err := ResolveEnv(&s, WithSource(MapSource{"ENV_A_HOST": "a", "ENV_B_PORT": "1"}))
+
+					// Assert
+					Expect(err).To(MatchError(ErrEnvParseFailure))
+					Expect(err.Error()).To(Equal("failed to parse env tags:\n" +
+						"Primary: 'prefix' and 'inline' cannot be combined\n" +
+						"Replica: 'required' does not apply to nested structs; mark their fields instead"))
+					Expect(s.Primary).To(BeZero())
+					Expect(s.Replica).To(BeNil())
+					Expect(s.Fallback).To(Equal(DBStruct{Host: "localhost", Port: 1}))
+				})
+
+				It("will report prefixes on fields that are not nested", func() {
+					// Arrange
+					type BadStruct struct {
+						Host    string        `envp:"host,prefix=primary"`
+						Timeout time.Duration `envp:"timeout,inline"`
+					}
+
+					// Act
+					var s BadStruct
+					err := ResolveEnv(&s, WithSource(MapSource{"ENV_HOST": "a", "ENV_TIMEOUT": "1s"}))
+
+					// Assert
+					Expect(err).To(MatchError(ErrEnvParseFailure))
+					Expect(err.Error()).To(Equal("failed to parse env tags:\n" +
+						"Host (ENV_HOST): 'prefix' and 'inline' only apply to nested structs\n" +
+						"Timeout (ENV_TIMEOUT): 'prefix' and 'inline' only apply to nested structs"))
+					Expect(s).To(BeZero())
+				})
+			})
+		})
+
+		Context("required fields and errors", func() {
+			type InnerStruct struct {
+				Timeout int    `envp:"timeout"`
+				Token   string `envp:"api_password,required"`
+			}
+			type TestStruct struct {
+				Host  string  `envp:"host,required"`
+				Port  int     `envp:"port,required,default=80"`
+				Ratio float64 `envp:"ratio"`
+				Inner *InnerStruct
+			}
+
+			It("will accept required fields that have values", func() {
+				// Act
+				var s TestStruct
+				err := ResolveEnvWithName("test", &s, WithSource(MapSource{"ENV_HOST": "monty", "ENV_TEST_API_PASSWORD": "x"}))
+
+				// Assert
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.Host).To(Equal("monty"))
+				Expect(s.Port).To(Equal(80))
+				Expect(s.Inner.Token).To(Equal("x"))
+			})
+
+			It("will report every field that is missing or cannot be parsed", func() {
+				// Arrange
+				source := MapSource{"ENV_TEST_TIMEOUT": "soon", "ENV_PORT": "", "ENV_RATIO": "1.5"}
+
+				// Act
+				var s TestStruct
+				err := ResolveEnvWithName("test", &s, WithSource(source))
+
+				// Assert
+				Expect(err).To(MatchError(ErrEnvParseFailure))
+				Expect(err).To(MatchError(ErrEnvNotSet))
+				Expect(err.Error()).To(Equal("failed to parse env tags:\n" +
+					"Host (ENV_TEST_HOST, ENV_HOST): required value is not set\n" +
+					"Inner.Timeout (ENV_TEST_TIMEOUT, ENV_TIMEOUT): strconv.ParseInt: parsing \"soon\": invalid syntax\n" +
+					"Inner.Token (ENV_TEST_API_PASSWORD, ENV_API_PASSWORD): required value is not set"))
+				Expect(s.Port).To(Equal(80))
+				Expect(s.Ratio).To(Equal(1.5))
+			})
+
+			It("will only try the base variable without a name", func() {
+				// Act
+				var s TestStruct
+				err := ResolveEnv(&s, WithSource(MapSource{"ENV_API_PASSWORD": "x", "ENV__HOST": "ignored"}))
+
+				// Assert
+				Expect(err).To(MatchError(ErrEnvNotSet))
+				Expect(err.Error()).To(Equal("failed to parse env tags: Host (ENV_HOST): required value is not set"))
+				Expect(s.Ratio).To(BeZero())
+			})
+
+			It("will describe each field with a FieldError", func() {
+				// Arrange
+				type PoolStruct struct {
+					Max int `envp:"pool_max"`
+				}
+				type DBStruct struct {
+					Pool     PoolStruct
+					Password int `envp:"db_password"`
+				}
+				type ConfigStruct struct {
+					DB *DBStruct
+				}
+				source := MapSource{"ENV_POOL_MAX": "lots", "ENV_TEST_DB_PASSWORD": "hunter2"}
+
+				// Act
+				var s ConfigStruct
+				err := ResolveEnvWithName("test", &s, WithSource(source))
+
+				// Assert
+				Expect(err).To(MatchError(ErrEnvParseFailure))
+				var fieldErr *FieldError
+				Expect(errors.As(err, &fieldErr)).To(BeTrue())
+				Expect(fieldErr.Path).To(Equal("DB.Pool.Max"))
+				Expect(fieldErr.EnvVar).To(Equal("ENV_POOL_MAX"))
+				Expect(fieldErr.Tried).To(Equal([]string{"ENV_TEST_POOL_MAX", "ENV_POOL_MAX"}))
+				Expect(fieldErr.Value).To(Equal("lots"))
+				Expect(fieldErr.Type).To(Equal(reflect.TypeFor[int]()))
+				var numErr *strconv.NumError
+				Expect(errors.As(fieldErr, &numErr)).To(BeTrue())
+				Expect(fieldErr).To(MatchError(ErrEnvParseFailure))
+
+				Expect(err.Error()).ToNot(ContainSubstring("hunter2"))
+			})
+
+			It("will redact secret values in a FieldError", func() {
+				// Arrange
+				type SecretStruct struct {
+					Password int `envp:"db_password"`
+				}
+
+				// Act
+				var s SecretStruct
+				err := ResolveEnvWithName("test", &s, WithSource(MapSource{"ENV_TEST_DB_PASSWORD": "hunter2"}))
+
+				// Assert
+				var fieldErr *FieldError
+				Expect(errors.As(err, &fieldErr)).To(BeTrue())
+				Expect(fieldErr.EnvVar).To(Equal("ENV_TEST_DB_PASSWORD"))
+				Expect(fieldErr.Value).To(Equal(RedactedValue))
+				Expect(fieldErr.Error()).ToNot(ContainSubstring("hunter2"))
+			})
+
+			It("will redact the elements of secret lists", func() {
+				// Arrange
+				type SecretStruct struct {
+					Timeouts []time.Duration     `envp:"api_token"`
+					Keys     map[string]*big.Int `envp:"keys_secret"`
+				}
+				source := MapSource{"ENV_API_TOKEN": "1s,hunter2", "ENV_KEYS_SECRET": "a=1,b=hunter3"}
+
+				// Act
+				var s SecretStruct
+				err := ResolveEnv(&s, WithSource(source))
+
+				// Assert
+				Expect(err).To(MatchError(ErrEnvParseFailure))
+				Expect(err.Error()).ToNot(ContainSubstring("hunter"))
+				Expect(err.Error()).To(ContainSubstring("Timeouts (ENV_API_TOKEN): invalid value " + RedactedValue))
+				Expect(err.Error()).To(ContainSubstring("Keys (ENV_KEYS_SECRET): invalid value " + RedactedValue))
+			})
+
+			It("will not name a variable for a bad default", func() {
+				// Arrange
+				type DefaultStruct struct {
+					Port int `envp:"port,default=http"`
+				}
+
+				// Act
+				var s DefaultStruct
+				err := ResolveEnvWithName("test", &s, WithSource(MapSource{}))
+
+				// Assert
+				var fieldErr *FieldError
+				Expect(errors.As(err, &fieldErr)).To(BeTrue())
+				Expect(fieldErr.EnvVar).To(BeEmpty())
+				Expect(fieldErr.FromDefault).To(BeTrue())
+				Expect(fieldErr.Tried).To(Equal([]string{"ENV_TEST_PORT", "ENV_PORT"}))
+				Expect(fieldErr.Value).To(Equal("http"))
+			})
+
+			It("will report missing values with a FieldError", func() {
+				// Act
+				var s TestStruct
+				err := ResolveEnvWithName("test", &s, WithSource(MapSource{"ENV_TEST_API_PASSWORD": "x"}))
+
+				// Assert
+				var fieldErr *FieldError
+				Expect(errors.As(err, &fieldErr)).To(BeTrue())
+				Expect(fieldErr.Path).To(Equal("Host"))
+				Expect(fieldErr.EnvVar).To(BeEmpty())
+				Expect(fieldErr.FromDefault).To(BeFalse())
+				Expect(fieldErr.Tried).To(Equal([]string{"ENV_TEST_HOST", "ENV_HOST"}))
+				Expect(fieldErr.Value).To(BeEmpty())
+				Expect(fieldErr.Err).To(Equal(ErrEnvNotSet))
+				Expect(fieldErr).To(MatchError(ErrEnvParseFailure))
+			})
 		})
 	})
 })
@@ -0,0 +1,65 @@
+package env
+
+import (
+	"testing"
+)
+
+// Applies the environment for the duration of a test, and registers the revert with t.Cleanup so
+// that the environment is restored when the test (and its subtests) complete.
+//
+// The test fails immediately if the Setup cannot be applied (see ApplyE).
+//
+// For Ginkgo specs, see the envginkgo package.
+func (a Setup) ApplyT(t testing.TB) {
+	t.Helper()
+
+	revert, err := a.ApplyE()
+	if err != nil {
+		t.Fatalf("env: %v", err)
+		return
+	}
+	t.Cleanup(func() { revert.Apply() })
+}
+
+// Fails the test if it leaves the process environment changed, e.g. by calling os.Setenv without
+// reverting.  The environment is captured now and compared with the environment when the test
+// (and its subtests) complete; variables whose keys match any of the 'ignore' patterns (see
+// path.Match) are not compared.  For example:
+//
+//	func TestSomething(t *testing.T) {
+//	    env.DetectLeaks(t, "GOCOVERDIR")
+//	    ...
+//	}
+//
+// Call it before anything else that registers a cleanup (such as ApplyT) so that it runs last.
+// Leaked changes are reported with t.Errorf and then reverted, so that later tests are unaffected.
+//
+// For Ginkgo specs, see the envginkgo package.
+func DetectLeaks(t testing.TB, ignore ...string) {
+	t.Helper()
+
+	snapshot := Capture()
+	t.Cleanup(func() {
+		t.Helper()
+
+		changes, revert := ChangesSince(snapshot, ignore...)
+		if len(changes) > 0 {
+			t.Errorf("env: test leaked changes to the environment:\n%v", changes)
+			revert.Apply()
+		}
+	})
+}
+
+// Applies the environment, calls fn and then reverts the environment, returning the error from fn.
+//
+// The environment is reverted even if fn panics.  If the Setup cannot be applied then fn is not
+// called and the error from ApplyE is returned.
+func (a Setup) With(fn func() error) error {
+	revert, err := a.ApplyE()
+	if err != nil {
+		return err
+	}
+	defer revert.Apply()
+
+	return fn()
+}
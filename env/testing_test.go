@@ -0,0 +1,159 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing helpers", func() {
+	const name = "__test_testing_helpers__"
+
+	BeforeEach(func() {
+		origEnv := New().Set(name, "original").Apply()
+		DeferCleanup(func() { origEnv.Apply() })
+	})
+
+	Context("ApplyT", func() {
+		It("should apply now and revert on cleanup", func() {
+			// Arrange
+			t := &fakeTB{}
+
+			// Act
+			New().Set(name, "applied").ApplyT(t)
+
+			// Assert
+			Expect(os.Getenv(name)).To(Equal("applied"))
+			Expect(t.cleanups).To(HaveLen(1))
+			t.cleanups[0]()
+			Expect(os.Getenv(name)).To(Equal("original"))
+		})
+
+		It("should fail the test if the setup cannot be applied", func() {
+			// Arrange
+			t := &fakeTB{}
+
+			// Act
+			New().Set(name, "applied").Set("BAD=KEY", "x").ApplyT(t)
+
+			// Assert
+			Expect(t.fatal).To(ContainSubstring(`key "BAD=KEY" contains '='`))
+			Expect(os.Getenv(name)).To(Equal("original"))
+		})
+	})
+
+	Context("With", func() {
+		It("should apply while the function runs and revert afterwards", func() {
+			// Arrange
+			expectedErr := errors.New("from fn")
+			var seen string
+
+			// Act
+			err := New().Set(name, "applied").With(func() error {
+				seen = os.Getenv(name)
+				return expectedErr
+			})
+
+			// Assert
+			Expect(err).To(MatchError(expectedErr))
+			Expect(seen).To(Equal("applied"))
+			Expect(os.Getenv(name)).To(Equal("original"))
+		})
+
+		It("should revert when the function panics", func() {
+			// Act
+			Expect(func() {
+				_ = New().Set(name, "applied").With(func() error {
+					panic("boom")
+				})
+			}).To(PanicWith("boom"))
+
+			// Assert
+			Expect(os.Getenv(name)).To(Equal("original"))
+		})
+
+		It("should not call the function if the setup cannot be applied", func() {
+			// Arrange
+			called := false
+
+			// Act
+			err := New().Set("BAD=KEY", "x").With(func() error {
+				called = true
+				return nil
+			})
+
+			// Assert
+			Expect(err).To(MatchError(ErrApplyFailure))
+			Expect(called).To(BeFalse())
+		})
+	})
+
+	Context("DetectLeaks", func() {
+		const leaked = "__test_detect_leaks__"
+		const ignored = "__test_detect_leaks_ignored__"
+
+		BeforeEach(func() {
+			origEnv := New().Unset(leaked).Unset(ignored).Apply()
+			DeferCleanup(func() { origEnv.Apply() })
+		})
+
+		It("should pass when the test reverts its changes", func() {
+			// Arrange
+			t := &fakeTB{}
+			DetectLeaks(t)
+			New().Set(name, "applied").ApplyT(t)
+
+			// Act
+			for index := len(t.cleanups) - 1; index >= 0; index-- {
+				t.cleanups[index]()
+			}
+
+			// Assert
+			Expect(t.fatal).To(BeEmpty())
+		})
+
+		It("should report and revert leaked changes", func() {
+			// Arrange
+			t := &fakeTB{}
+			DetectLeaks(t, "__test_detect_leaks_ign*")
+			os.Setenv(leaked, "leaked")
+			os.Setenv(ignored, "ignored")
+			os.Unsetenv(name)
+
+			// Act
+			t.cleanups[0]()
+
+			// Assert
+			Expect(t.fatal).To(Equal(fmt.Sprintf("env: test leaked changes to the environment:\n- %s=original\n+ %s=leaked", name, leaked)))
+			_, ok := os.LookupEnv(leaked)
+			Expect(ok).To(BeFalse())
+			Expect(os.Getenv(name)).To(Equal("original"))
+			Expect(os.Getenv(ignored)).To(Equal("ignored"))
+		})
+	})
+})
+
+// records Fatalf, Errorf and Cleanup calls instead of acting on them; calling any other method panics
+type fakeTB struct {
+	testing.TB
+	fatal    string
+	cleanups []func()
+}
+
+func (t *fakeTB) Helper() {}
+
+func (t *fakeTB) Fatalf(format string, args ...any) {
+	t.fatal = fmt.Sprintf(format, args...)
+}
+
+func (t *fakeTB) Errorf(format string, args ...any) {
+	t.fatal = fmt.Sprintf(format, args...)
+}
+
+func (t *fakeTB) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
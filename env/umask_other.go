@@ -0,0 +1,12 @@
+//go:build !unix
+
+package env
+
+import (
+	"io/fs"
+)
+
+// there is no umask on this platform
+func setUmask(fs.FileMode) (fs.FileMode, bool) {
+	return 0, false
+}
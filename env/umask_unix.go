@@ -0,0 +1,13 @@
+//go:build unix
+
+package env
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// sets the umask, returning the previous one
+func setUmask(mask fs.FileMode) (fs.FileMode, bool) {
+	return fs.FileMode(syscall.Umask(int(mask))), true // #nosec G115 -- validated to be permission bits
+}
@@ -1,15 +1,30 @@
 package resolver
 
 import (
-	"os"
+	"github.com/keithpaterson/go-tools/env"
 )
 
 type envResolver struct {
 	ResolverImpl
+	source env.Source
 }
 
-func NewEnvResolver() *envResolver {
-	return &envResolver{}
+// Customizes the behaviour of the environment resolver.
+type EnvResolverOption func(r *envResolver)
+
+// Resolves variables from the given Source instead of the process environment.
+func WithEnvSource(source env.Source) EnvResolverOption {
+	return func(r *envResolver) {
+		r.source = source
+	}
+}
+
+func NewEnvResolver(opts ...EnvResolverOption) *envResolver {
+	r := &envResolver{source: env.OSSource}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *envResolver) Resolve(name string, token string) (string, bool) {
@@ -17,7 +32,7 @@ func (r *envResolver) Resolve(name string, token string) (string, bool) {
 		return token, false
 	}
 
-	envValue, ok := os.LookupEnv(token)
+	envValue, ok := r.source.LookupEnv(token)
 	if !ok {
 		return token, false
 	}
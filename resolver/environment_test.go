@@ -46,6 +46,21 @@ var _ = Describe("Environment Resolver", func() {
 		),
 	)
 
+	It("will resolve from the source provided with WithEnvSource", func() {
+		// Arrange
+		origEnv := env.New().Set("input", "process").Apply()
+		defer origEnv.Apply()
+		resolver = NewEnvResolver(WithEnvSource(env.MapSource{"input": "${env:foo}", "foo": "mapped"}))
+		root.WithResolver("env", resolver)
+
+		// Act
+		actual, ok := resolver.Resolve("env", "input")
+
+		// Assert
+		Expect(ok).To(BeTrue())
+		Expect(actual).To(Equal("mapped"))
+	})
+
 	It("will ignore requests for invalid token name", func() {
 		// Arrange
 